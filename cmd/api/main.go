@@ -3,18 +3,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/sinspired/checkip/internal/config"
 	"github.com/sinspired/checkip/internal/data"
 	"github.com/sinspired/checkip/internal/resolver"
 	"github.com/sinspired/checkip/internal/server"
+	"github.com/sinspired/checkip/pkg/ipinfo"
 )
 
 const (
@@ -28,7 +32,22 @@ func ensureEnvFile() {
 		defaultEnv := `ADDR=:8099
 PORT=8099
 MAXMIND_DB_PATH=
+MAXMIND_ASN_DB_PATH=
+GEOIP_UPDATE_SOURCE=github
+GEOIP_UPDATE_URL=
+GEOIP_UPDATE_CHECKSUM_URL=
+GEOIP_UPDATE_DIR=
+MAXMIND_ACCOUNT_ID=
+MAXMIND_LICENSE_KEY=
+MAXMIND_EDITION_ID=
 CF_CIDR_PATH=
+CDN_RELOAD_INTERVAL=30s
+CDN_RANGE_FILES=
+CF_IPV4_URL=
+CF_IPV6_URL=
+CF_REFRESH_INTERVAL=24h
+TAG_TEMPLATE=
+PROBES_FILE=
 HTTP_TIMEOUT=10s
 MAX_RETRIES=3
 LOG_LEVEL=info
@@ -38,7 +57,34 @@ GITHUB_PROXY="https://ghproxy.net/"
 	}
 }
 
-func UpdateCronJob(dbPath string) {
+// newUpdateSource 根据配置选择数据库更新源
+func newUpdateSource(cfg *config.Config) (data.UpdateSource, error) {
+	switch cfg.GeoIPUpdateSource {
+	case "", "github":
+		return data.DefaultUpdateSource(), nil
+	case "maxmind":
+		return &data.MaxMindSource{
+			AccountID:  cfg.MaxMindAccountID,
+			LicenseKey: cfg.MaxMindLicenseKey,
+			EditionID:  cfg.MaxMindEditionID,
+		}, nil
+	case "dbip":
+		// 更新的是 City 数据库, 只能使用 city-lite
+		return &data.DBIPLiteSource{Edition: "city-lite"}, nil
+	case "ipinfo":
+		// IPinfo Lite 的记录结构与 MaxMind 不兼容, 无法替换 City 数据库
+		return nil, fmt.Errorf("IPinfo Lite 与 GeoLite2-City 格式不兼容, 不能作为数据库更新源")
+	case "url":
+		return &data.URLSource{URL: cfg.GeoIPUpdateURL, ChecksumURL: cfg.GeoIPUpdateChecksumURL}, nil
+	case "dir":
+		return &data.DirSource{Dir: cfg.GeoIPUpdateDir}, nil
+	default:
+		return nil, fmt.Errorf("未知的数据库更新源: %s", cfg.GeoIPUpdateSource)
+	}
+}
+
+// UpdateCronJob 每周日从 src 更新数据库, 成功后调用 onUpdate 使运行中的服务加载新数据库
+func UpdateCronJob(src data.UpdateSource, dbPath string, onUpdate func()) {
 	if dbPath == "" {
 		return
 	}
@@ -57,16 +103,37 @@ func UpdateCronJob(dbPath string) {
 			time.Sleep(wait)
 
 			// 在计划时间执行更新
-			if err := data.UpdateGeoLite2DB(dbPath); err != nil {
-				slog.Warn("MaxMind 更新失败", "error", err)
+			status, err := data.UpdateDB(context.Background(), nil, src, dbPath)
+			if err != nil {
+				slog.Warn("MaxMind 更新失败", "status", status, "error", err)
 			} else {
-				slog.Info("MaxMind 数据库已更新", "path", dbPath)
+				slog.Info("MaxMind 数据库更新检查完成", "status", status, "path", dbPath)
+				if status == data.UpdateUpdated && onUpdate != nil {
+					onUpdate()
+				}
 			}
 			// 循环继续，下一次会重新计算（处理 DST 及其它时间变化）
 		}
 	}()
 }
 
+// UpdateCfRangesJob 启动时及每隔 interval 更新 Cloudflare 网段, 失败时沿用缓存或内置网段
+func UpdateCfRangesJob(v4URL, v6URL string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if err := ipinfo.MergeCloudflareRanges(ctx, nil, v4URL, v6URL); err != nil {
+				slog.Warn("Cloudflare 网段更新失败", "error", err)
+			}
+			cancel()
+			time.Sleep(interval)
+		}
+	}()
+}
+
 func main() {
 	// 自动创建 .env 文件
 	ensureEnvFile()
@@ -74,35 +141,116 @@ func main() {
 	// 加载配置
 	cfg := config.Load()
 
-	// 加载 Cloudflare CIDR 数据
-	cidrs := data.GetCfCdnIPRanges()
+	// 定期更新内置 Cloudflare 网段
+	UpdateCfRangesJob(cfg.CFIPv4URL, cfg.CFIPv6URL, cfg.CFRefreshInterval)
+
+	updateSrc, err := newUpdateSource(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	// 仅当未指定外部路径且文件存在时才检查更新
+	var updatePath string
 	if cfg.MaxMindDBPath == "" {
 		dataPath := data.ResolveDataPath()
 		dbPath := filepath.Join(dataPath, dbFileName)
-		// 添加一个定时更新任务
-		UpdateCronJob(dbPath)
-		// 如果文件存在,检查是否过期
+		updatePath = dbPath
+		// 如果文件存在, 距上次检查(无记录时按文件修改时间)超过更新间隔则立即检查更新
 		if fi, err := os.Stat(dbPath); err == nil {
-			if time.Since(fi.ModTime()) > updateInterval {
-				// 立即更新数据库
-				_ = data.UpdateGeoLite2DB(dbPath)
+			checkedAt := fi.ModTime()
+			if st, err := data.LoadUpdateState(dbPath); err == nil && st.CheckedAt.After(checkedAt) {
+				checkedAt = st.CheckedAt
+			}
+			if time.Since(checkedAt) > updateInterval {
+				status, err := data.UpdateDB(context.Background(), nil, updateSrc, dbPath)
+				slog.Info("MaxMind 数据库更新检查完成", "status", status, "error", err)
 			}
 		}
 	}
 
-	// 打开 MaxMind 数据库（为空时自动解压内置库）
-	geo, err := data.OpenMaxMindDB(cfg.MaxMindDBPath)
+	// 打开 MaxMind 数据库（为空时自动解压内置库）, 更新后热替换
+	geo, err := ipinfo.OpenReloadableDB(cfg.MaxMindDBPath)
 	if err != nil {
 		log.Fatalf("打开 MaxMind 数据库失败: %v", err)
 	}
 	defer geo.Close()
 
+	opts := []ipinfo.Option{ipinfo.WithReloadableDB(geo)}
+
+	// 打开 ASN 数据库（可选, 不可用时跳过 ASN 查询）
+	if asn, err := data.OpenMaxMindASNDB(cfg.MaxMindASNDBPath); err == nil {
+		defer asn.Close()
+		opts = append(opts, ipinfo.WithASNDBReader(asn))
+	} else {
+		slog.Warn("ASN 数据库不可用", "error", err)
+	}
+
+	// 加载自定义 CDN 网段文件
+	for name, path := range cfg.CDNRangeFiles {
+		opts = append(opts, ipinfo.WithCDNRangeFile(name, path))
+	}
+	if cfg.CFCIDRPath != "" {
+		opts = append(opts, ipinfo.WithExtraCDNRangeFile(ipinfo.CDNCloudflare, cfg.CFCIDRPath))
+	}
+	if cfg.CDNReloadInterval > 0 {
+		opts = append(opts, ipinfo.WithCDNReload(cfg.CDNReloadInterval))
+	}
+
+	// 自定义节点标签
+	if cfg.TagTemplate != "" {
+		f, err := ipinfo.NewTagFormatter(cfg.TagTemplate)
+		if err != nil {
+			log.Fatalf("标签模板无效: %v", err)
+		}
+		opts = append(opts, ipinfo.WithTagFormatter(f))
+	}
+
+	// 服务可达性探测
+	if cfg.ProbesFile != "" {
+		probes, err := ipinfo.LoadProbesFile(cfg.ProbesFile)
+		if err != nil {
+			log.Fatalf("加载探测定义失败: %v", err)
+		}
+		opts = append(opts, ipinfo.WithProbes(probes...))
+	}
+
+	// 兼容旧的 SUBS-CHECK-CALL 环境变量: 在境内运行时拒绝 CN 结果
+	if os.Getenv("SUBS-CHECK-CALL") != "" {
+		opts = append(opts, ipinfo.WithRejectCountries("CN"))
+	}
+
 	// 创建检查器
-	ck := resolver.NewResolver(cidrs, geo)
+	// 使用内置网段(随运行时更新)及上面指定的网段文件
+	ck, err := resolver.NewResolver(nil, nil, opts...)
+	if err != nil {
+		log.Fatalf("创建检查器失败: %v", err)
+	}
+	defer ck.Close()
+
+	// 添加一个定时更新任务, 更新成功后热替换数据库
+	UpdateCronJob(updateSrc, updatePath, func() {
+		if err := ck.ReloadDB(); err != nil {
+			slog.Warn("MaxMind 数据库重新加载失败, 继续使用原数据库", "error", err)
+		}
+	})
 	h := &server.Handler{Resolver: ck}
 
+	// 收到 SIGHUP 时重新加载网段文件及数据库
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			if err := ck.Reload(); err != nil {
+				slog.Warn("CDN 网段重新加载失败", "error", err)
+			} else {
+				slog.Info("CDN 网段已重新加载")
+			}
+			if err := ck.ReloadDB(); err != nil {
+				slog.Warn("MaxMind 数据库重新加载失败", "error", err)
+			}
+		}
+	}()
+
 	// 设置路由
 	mux := http.NewServeMux()
 	mux.Handle("/api/", h)
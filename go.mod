@@ -6,6 +6,7 @@ require (
 	github.com/klauspost/compress v1.18.5
 	github.com/metacubex/mihomo v1.19.21
 	github.com/oschwald/maxminddb-golang/v2 v2.1.1
+	golang.org/x/text v0.35.0
 )
 
 require (
@@ -41,5 +42,4 @@ require (
 	golang.org/x/exp v0.0.0-20260312153236-7ab1446f8b90 // indirect
 	golang.org/x/net v0.52.0 // indirect
 	golang.org/x/sys v0.42.0 // indirect
-	golang.org/x/text v0.35.0 // indirect
 )
@@ -4,6 +4,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,7 +15,34 @@ type Config struct {
 	Port int
 
 	// 数据库配置
-	MaxMindDBPath string
+	MaxMindDBPath    string
+	MaxMindASNDBPath string // 为空时使用数据目录或内置 ASN 数据库, 均不存在则不查询 ASN
+
+	// 数据库更新源: github(默认)、maxmind、dbip、url、dir, 仅在未指定 MaxMindDBPath 时更新
+	GeoIPUpdateSource      string
+	GeoIPUpdateURL         string // url 更新源的下载地址
+	GeoIPUpdateChecksumURL string // url 更新源的 SHA-256 校验和地址, 可选
+	GeoIPUpdateDir         string // dir 更新源的目录
+	MaxMindAccountID       string
+	MaxMindLicenseKey      string
+	MaxMindEditionID       string // 默认 GeoLite2-City
+
+	// CDN 网段文件, 名称 -> 路径, 同名时替换内置网段
+	CDNRangeFiles map[string]string
+	// 追加到 Cloudflare 网段的文件
+	CFCIDRPath string
+	// 网段文件检查间隔, 为 0 时仅在收到 SIGHUP 时重新加载
+	CDNReloadInterval time.Duration
+
+	// Cloudflare 网段更新, 地址为空时使用官方地址, 间隔为 0 时不更新
+	CFIPv4URL         string
+	CFIPv6URL         string
+	CFRefreshInterval time.Duration
+
+	// 节点标签模板(text/template), 为空时使用内置格式
+	TagTemplate string
+	// 服务可达性探测定义文件(JSON), 为空时不探测
+	ProbesFile string
 
 	// HTTP 客户端配置
 	HTTPTimeout time.Duration
@@ -27,12 +55,28 @@ type Config struct {
 // Load 从环境变量加载配置
 func Load() *Config {
 	cfg := &Config{
-		Addr:          getEnv("ADDR", ":8099"),
-		Port:          getEnvAsInt("PORT", 8099),
-		MaxMindDBPath: getEnv("MAXMIND_DB_PATH", ""),
-		HTTPTimeout:   getEnvAsDuration("HTTP_TIMEOUT", 10*time.Second),
-		MaxRetries:    getEnvAsInt("MAX_RETRIES", 3),
-		LogLevel:      getEnv("LOG_LEVEL", "info"),
+		Addr:                   getEnv("ADDR", ":8099"),
+		Port:                   getEnvAsInt("PORT", 8099),
+		MaxMindDBPath:          getEnv("MAXMIND_DB_PATH", ""),
+		MaxMindASNDBPath:       getEnv("MAXMIND_ASN_DB_PATH", ""),
+		GeoIPUpdateSource:      getEnv("GEOIP_UPDATE_SOURCE", "github"),
+		GeoIPUpdateURL:         getEnv("GEOIP_UPDATE_URL", ""),
+		GeoIPUpdateChecksumURL: getEnv("GEOIP_UPDATE_CHECKSUM_URL", ""),
+		GeoIPUpdateDir:         getEnv("GEOIP_UPDATE_DIR", ""),
+		MaxMindAccountID:       getEnv("MAXMIND_ACCOUNT_ID", ""),
+		MaxMindLicenseKey:      getEnv("MAXMIND_LICENSE_KEY", ""),
+		MaxMindEditionID:       getEnv("MAXMIND_EDITION_ID", ""),
+		CDNRangeFiles:          getEnvAsMap("CDN_RANGE_FILES"),
+		CFCIDRPath:             getEnv("CF_CIDR_PATH", ""),
+		CDNReloadInterval:      getEnvAsDuration("CDN_RELOAD_INTERVAL", 30*time.Second),
+		CFIPv4URL:              getEnv("CF_IPV4_URL", ""),
+		CFIPv6URL:              getEnv("CF_IPV6_URL", ""),
+		CFRefreshInterval:      getEnvAsDuration("CF_REFRESH_INTERVAL", 24*time.Hour),
+		TagTemplate:            getEnv("TAG_TEMPLATE", ""),
+		ProbesFile:             getEnv("PROBES_FILE", ""),
+		HTTPTimeout:            getEnvAsDuration("HTTP_TIMEOUT", 10*time.Second),
+		MaxRetries:             getEnvAsInt("MAX_RETRIES", 3),
+		LogLevel:               getEnv("LOG_LEVEL", "info"),
 	}
 
 	return cfg
@@ -56,6 +100,22 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsMap 获取 name=value,name=value 形式的环境变量
+func getEnvAsMap(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && k != "" && v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
 // getEnvAsDuration 获取环境变量并转换为时间间隔
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
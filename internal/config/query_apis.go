@@ -77,3 +77,24 @@ var GEOIP_APIS = []string{
 	"https://ipapi.co/json",
 	// "https://ipinfo.io/json", // 准确,免费速率限制
 }
+
+// 仅返回 IPv4 出口的地址, 用于双栈检测
+var IPV4_APIS = []string{
+	"https://4.tnedi.me/ip",
+	"https://ipv4.seeip.org/ip",
+	"https://ip4.me/api/",
+	"https://ipv4.my.ipinfo.app/api/ipDetails.php",
+	"https://4.ident.me/json",
+	"https://4.tnedi.me/json",
+}
+
+// 仅返回 IPv6 出口的地址, 用于双栈检测
+var IPV6_APIS = []string{
+	"https://6.ident.me/ip",
+	"https://ipv6.seeip.org/ip",
+	"https://ip6.me/api/",
+	"https://ipv6.my.ipinfo.app/api/ipDetails.php",
+	"https://ipv6.wtfismyip.com/text",
+	"https://6.ipw.cn/",
+	"https://api6.ipify.org?format=json",
+}
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+//go:embed cdn/*.txt
+var embeddedCDN embed.FS
+
+// CDNCloudflare 内置 Cloudflare 网段的名称
+const CDNCloudflare = "cloudflare"
+
+// CDNRanges 返回当前的 CDN 网段文本, 键为 CDN 名称; Cloudflare 为运行时更新后的网段, 其余为内置网段.
+// 每行一个 CIDR、IP 或 AS 编号(如 AS54113), # 开头为注释
+func CDNRanges() map[string]string {
+	out := map[string]string{
+		CDNCloudflare: CfCdnRangesText(),
+	}
+	entries, _ := fs.ReadDir(embeddedCDN, "cdn")
+	for _, e := range entries {
+		b, err := fs.ReadFile(embeddedCDN, path.Join("cdn", e.Name()))
+		if err != nil {
+			continue
+		}
+		out[strings.TrimSuffix(e.Name(), ".txt")] = string(b)
+	}
+	return out
+}
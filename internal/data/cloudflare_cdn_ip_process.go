@@ -3,81 +3,157 @@ package data
 
 import (
 	"bufio"
+	"fmt"
 	"log"
 	"log/slog"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
+const (
+	cfCacheIPv4 = "cloudflare_ips_v4.txt" // 运行时更新的缓存文件, 位于 ResolveDataPath()
+	cfCacheIPv6 = "cloudflare_ips_v6.txt"
+)
+
+// cfRangeSet 当前生效的 Cloudflare 网段
+type cfRangeSet struct {
+	nets map[string][]*net.IPNet
+	text string // 每行一个 CIDR, 供 CDN 注册表解析
+}
+
 var (
-	cfCdnIPRanges map[string][]*net.IPNet
+	cfCdnIPRanges atomic.Pointer[cfRangeSet]
 	loadOnce      sync.Once
 	loadError     error
+
+	cfListenersMu sync.Mutex
+	cfListeners   []func()
 )
 
-// readCdnIPsRanges 读取嵌入的 Cloudflare CDN IP 范围
+// readCdnIPsRanges 读取嵌入的 Cloudflare CDN IP 范围, 并合并上次运行时更新的缓存
 func readCdnIPsRanges() {
-	cfCdnIPRanges = make(map[string][]*net.IPNet)
 	ipContents := map[string]string{
 		"ipv4": embeddedIPv4,
 		"ipv6": embeddedIPv6,
 	}
 
+	ranges := make(map[string][]*net.IPNet)
 	totalLoaded := 0
 	for version, content := range ipContents {
-		var ipNets []*net.IPNet
-		scanner := bufio.NewScanner(strings.NewReader(content))
-		lineCount := 0
-
-		for scanner.Scan() {
-			lineCount++
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
-
-			if !strings.Contains(line, "/") {
-				// 为单独ipv4添加一个/32后缀
-				if version == "ipv4" {
-					// 如果是 IPv4 地址且没有 CIDR 后缀，添加 /32
-					line = line + "/32"
-				} else {
-					// 如果是 IPv6 地址且没有 CIDR 后缀，添加 /128
-					line = line + "/128"
-				}
-			}
-			_, ipNet, err := net.ParseCIDR(line)
-			if err != nil {
-				log.Printf("Warning: Failed to parse CIDR %s on line %d: %v", line, lineCount, err)
-				continue
-			}
-			ipNets = append(ipNets, ipNet)
-			slog.Debug("Loaded Cloudflare CDN IP range",
-				slog.String("version", version),
-				slog.String("cidr", ipNet.String()))
-		}
-
-		if err := scanner.Err(); err != nil {
+		ipNets, err := parseCfRanges(version, content, false)
+		if err != nil {
 			slog.Debug("Error reading IP ranges",
 				slog.String("version", version),
 				slog.Any("error", err))
 			loadError = err
 			return
 		}
-
-		cfCdnIPRanges[version] = ipNets
+		ranges[version] = ipNets
 		totalLoaded += len(ipNets)
 		slog.Debug("Loaded Cloudflare CDN IP ranges",
 			slog.Int("count", len(ipNets)),
 			slog.String("version", version))
 	}
 
+	// 缓存校验失败时忽略, 仅使用内置列表; 合并同时去除内置列表中的重复网段
+	cached, err := readCfCache(ResolveDataPath())
+	if err == nil {
+		slog.Debug("Merged cached Cloudflare CDN IP ranges")
+	}
+	ranges = mergeCfRanges(ranges, cached)
+
+	cfCdnIPRanges.Store(newCfRangeSet(ranges))
 	slog.Debug("Successfully loaded Cloudflare CDN IP ranges",
 		slog.Int("total_loaded", totalLoaded))
 }
 
-// GetCfCdnIPRanges 一次性加载 Cloudflare CDN IP 范围
+// parseCfRanges 解析网段列表, strict 为 true 时任意一行无效即返回错误
+func parseCfRanges(version, content string, strict bool) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineCount := 0
+
+	for scanner.Scan() {
+		lineCount++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.Contains(line, "/") {
+			// 为单独ipv4添加一个/32后缀
+			if version == "ipv4" {
+				// 如果是 IPv4 地址且没有 CIDR 后缀，添加 /32
+				line = line + "/32"
+			} else {
+				// 如果是 IPv6 地址且没有 CIDR 后缀，添加 /128
+				line = line + "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(line)
+		if err == nil && (ipNet.IP.To4() != nil) != (version == "ipv4") {
+			err = fmt.Errorf("地址族不匹配")
+		}
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("第 %d 行 CIDR 无效 %q: %w", lineCount, line, err)
+			}
+			log.Printf("Warning: Failed to parse CIDR %s on line %d: %v", line, lineCount, err)
+			continue
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, scanner.Err()
+}
+
+// mergeCfRanges 合并两组网段并去重
+func mergeCfRanges(a, b map[string][]*net.IPNet) map[string][]*net.IPNet {
+	out := make(map[string][]*net.IPNet, len(a))
+	for _, version := range []string{"ipv4", "ipv6"} {
+		seen := make(map[string]bool)
+		for _, n := range append(append([]*net.IPNet(nil), a[version]...), b[version]...) {
+			if key := n.String(); !seen[key] {
+				seen[key] = true
+				out[version] = append(out[version], n)
+			}
+		}
+	}
+	return out
+}
+
+func newCfRangeSet(ranges map[string][]*net.IPNet) *cfRangeSet {
+	var b strings.Builder
+	for _, version := range []string{"ipv4", "ipv6"} {
+		for _, n := range ranges[version] {
+			b.WriteString(n.String())
+			b.WriteByte('\n')
+		}
+	}
+	return &cfRangeSet{nets: ranges, text: b.String()}
+}
+
+// readCfCache 读取并校验运行时更新的缓存
+func readCfCache(dir string) (map[string][]*net.IPNet, error) {
+	ranges := make(map[string][]*net.IPNet)
+	for version, name := range map[string]string{"ipv4": cfCacheIPv4, "ipv6": cfCacheIPv6} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		ipNets, err := validateCfRanges(version, string(b))
+		if err != nil {
+			return nil, err
+		}
+		ranges[version] = ipNets
+	}
+	return ranges, nil
+}
+
+// GetCfCdnIPRanges 一次性加载 Cloudflare CDN IP 范围, 运行时更新后返回新的范围
 func GetCfCdnIPRanges() map[string][]*net.IPNet {
 	loadOnce.Do(readCdnIPsRanges)
 
@@ -86,10 +162,39 @@ func GetCfCdnIPRanges() map[string][]*net.IPNet {
 		return nil
 	}
 
-	if cfCdnIPRanges == nil || (len(cfCdnIPRanges["ipv4"]) == 0 && len(cfCdnIPRanges["ipv6"]) == 0) {
+	set := cfCdnIPRanges.Load()
+	if set == nil || (len(set.nets["ipv4"]) == 0 && len(set.nets["ipv6"]) == 0) {
 		slog.Debug("Warning: No CDN IP ranges loaded")
 		return nil
 	}
 
-	return cfCdnIPRanges
+	return set.nets
+}
+
+// CfCdnRangesText 当前生效的 Cloudflare 网段文本
+func CfCdnRangesText() string {
+	loadOnce.Do(readCdnIPsRanges)
+	if set := cfCdnIPRanges.Load(); set != nil {
+		return set.text
+	}
+	return embeddedIPv4 + "\n" + embeddedIPv6
+}
+
+// OnCfCdnRangesUpdate 注册 Cloudflare 网段更新后的回调
+func OnCfCdnRangesUpdate(fn func()) {
+	cfListenersMu.Lock()
+	defer cfListenersMu.Unlock()
+	cfListeners = append(cfListeners, fn)
+}
+
+// storeCfRanges 原子替换当前网段并通知回调
+func storeCfRanges(ranges map[string][]*net.IPNet) {
+	cfCdnIPRanges.Store(newCfRangeSet(ranges))
+
+	cfListenersMu.Lock()
+	listeners := append([]func(){}, cfListeners...)
+	cfListenersMu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
 }
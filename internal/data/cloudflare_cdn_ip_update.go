@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cloudflare 官方网段列表
+const (
+	CfIPv4URL = "https://www.cloudflare.com/ips-v4"
+	CfIPv6URL = "https://www.cloudflare.com/ips-v6"
+)
+
+const (
+	minCfIPv4Ranges = 10      // IPv4 最少网段数, 官方列表约 15 个, 过少视为异常响应
+	minCfIPv6Ranges = 5       // IPv6 最少网段数, 官方列表约 7 个
+	maxCfRanges     = 100000  // 单个地址族最多网段数
+	maxCfRangesBody = 8 << 20 // 单个列表最大响应体
+)
+
+// MergeCfCdnIPRanges 下载 Cloudflare 网段列表, 校验后缓存到 ResolveDataPath() 并与内置网段合并生效;
+// 只增不减, 以保留官方列表未包含的网段. 地址为空时使用官方地址, 失败时保留当前网段
+func MergeCfCdnIPRanges(ctx context.Context, hc *http.Client, v4URL, v6URL string) error {
+	return mergeCfCdnIPRanges(ctx, hc, v4URL, v6URL, ResolveDataPath())
+}
+
+func mergeCfCdnIPRanges(ctx context.Context, hc *http.Client, v4URL, v6URL, cacheDir string) error {
+	if hc == nil {
+		hc = &http.Client{Timeout: 30 * time.Second}
+	}
+	if v4URL == "" {
+		v4URL = CfIPv4URL
+	}
+	if v6URL == "" {
+		v6URL = CfIPv6URL
+	}
+
+	fetched := make(map[string][]*net.IPNet)
+	bodies := make(map[string]string)
+	for _, src := range []struct{ version, url string }{{"ipv4", v4URL}, {"ipv6", v6URL}} {
+		body, err := fetchText(ctx, hc, src.url)
+		if err != nil {
+			return fmt.Errorf("下载 Cloudflare %s 网段失败: %w", src.version, err)
+		}
+		ipNets, err := validateCfRanges(src.version, body)
+		if err != nil {
+			return fmt.Errorf("Cloudflare %s 网段校验失败: %w", src.version, err)
+		}
+		fetched[src.version] = ipNets
+		bodies[src.version] = body
+	}
+
+	// 先写缓存再替换, 缓存写入失败不影响本次更新
+	for version, name := range map[string]string{"ipv4": cfCacheIPv4, "ipv6": cfCacheIPv6} {
+		if err := writeFileAtomic(filepath.Join(cacheDir, name), []byte(bodies[version])); err != nil {
+			slog.Warn("Cloudflare 网段缓存写入失败", "error", err)
+		}
+	}
+
+	embedded := make(map[string][]*net.IPNet)
+	embedded["ipv4"], _ = parseCfRanges("ipv4", embeddedIPv4, false)
+	embedded["ipv6"], _ = parseCfRanges("ipv6", embeddedIPv6, false)
+
+	loadOnce.Do(readCdnIPsRanges)
+	storeCfRanges(mergeCfRanges(embedded, fetched))
+	slog.Info("Cloudflare 网段已更新",
+		slog.Int("ipv4", len(fetched["ipv4"])),
+		slog.Int("ipv6", len(fetched["ipv6"])))
+	return nil
+}
+
+// validateCfRanges 严格解析网段列表并检查数量
+func validateCfRanges(version, content string) ([]*net.IPNet, error) {
+	ipNets, err := parseCfRanges(version, content, true)
+	if err != nil {
+		return nil, err
+	}
+	minRanges := minCfIPv4Ranges
+	if version == "ipv6" {
+		minRanges = minCfIPv6Ranges
+	}
+	if n := len(ipNets); n < minRanges || n > maxCfRanges {
+		return nil, fmt.Errorf("网段数量 %d 超出范围 [%d, %d]", n, minRanges, maxCfRanges)
+	}
+	return ipNets, nil
+}
+
+// fetchText 下载文本, 非 200 状态或超出大小限制时返回错误
+func fetchText(ctx context.Context, hc *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &httpStatusError{code: resp.StatusCode}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCfRangesBody+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxCfRangesBody {
+		return "", fmt.Errorf("响应超过 %d 字节", maxCfRangesBody)
+	}
+	return string(body), nil
+}
+
+// writeFileAtomic 写入临时文件后重命名, 避免读到写了一半的文件
+func writeFileAtomic(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,81 @@
+package data
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestMergeCfCdnIPRanges(t *testing.T) {
+	bodies := map[string]string{
+		"/ips-v4": "198.51.100.0/24\n203.0.113.0/24\n192.0.2.0/24\n198.18.0.0/24\n198.18.1.0/24\n" +
+			"198.18.2.0/24\n198.18.3.0/24\n198.18.4.0/24\n198.18.5.0/24\n198.18.6.0/24\n",
+		"/ips-v6": "2001:db8::/32\n2001:db9::/32\n2001:dba::/32\n2001:dbb::/32\n2001:dbc::/32\n",
+		"/few":    "198.51.100.0/24\n",
+		"/html":   "<html>captive portal</html>",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	// 恢复全局网段, 避免影响其他测试
+	GetCfCdnIPRanges()
+	prev := cfCdnIPRanges.Load()
+	t.Cleanup(func() { cfCdnIPRanges.Store(prev) })
+
+	notified := 0
+	OnCfCdnRangesUpdate(func() { notified++ })
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// 校验失败或下载失败时保留当前网段
+	if err := mergeCfCdnIPRanges(ctx, nil, srv.URL+"/html", srv.URL+"/ips-v6", dir); err == nil {
+		t.Error("无效列表应返回错误")
+	}
+	if err := mergeCfCdnIPRanges(ctx, nil, srv.URL+"/ips-v4", srv.URL+"/missing", dir); err == nil {
+		t.Error("下载失败应返回错误")
+	}
+	if err := mergeCfCdnIPRanges(ctx, nil, srv.URL+"/few", srv.URL+"/ips-v6", dir); err == nil {
+		t.Error("网段过少应返回错误")
+	}
+	if cfCdnIPRanges.Load() != prev || notified != 0 {
+		t.Error("更新失败时不应替换当前网段")
+	}
+
+	if err := mergeCfCdnIPRanges(ctx, nil, srv.URL+"/ips-v4", srv.URL+"/ips-v6", dir); err != nil {
+		t.Fatalf("更新失败: %v", err)
+	}
+	if notified != 1 {
+		t.Errorf("更新回调次数错误: %d", notified)
+	}
+
+	ranges := GetCfCdnIPRanges()
+	var v4 []string
+	for _, n := range ranges["ipv4"] {
+		v4 = append(v4, n.String())
+	}
+	if !slices.Contains(v4, "198.51.100.0/24") {
+		t.Error("未合并下载的网段")
+	}
+	if len(ranges["ipv4"]) < len(prev.nets["ipv4"]) {
+		t.Error("内置网段丢失")
+	}
+
+	// 缓存可在下次启动时加载
+	cached, err := readCfCache(dir)
+	if err != nil {
+		t.Fatalf("读取缓存失败: %v", err)
+	}
+	if len(cached["ipv4"]) != 10 || len(cached["ipv6"]) != 5 {
+		t.Errorf("缓存内容错误: %v", cached)
+	}
+}
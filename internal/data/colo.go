@@ -0,0 +1,13 @@
+package data
+
+import (
+	_ "embed"
+)
+
+//go:embed colo.csv
+var embeddedColo string
+
+// ColoTable 返回内置 Cloudflare 数据中心表, 每行 code,city,country,latitude,longitude, # 开头为注释
+func ColoTable() string {
+	return embeddedColo
+}
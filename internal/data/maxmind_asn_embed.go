@@ -0,0 +1,14 @@
+//go:build asn_embed
+
+package data
+
+import (
+	_ "embed"
+)
+
+//go:embed GeoLite2-ASN.mmdb.zst
+var embeddedMaxMindDBASN []byte
+
+func init() {
+	EmbeddedMaxMindDBASN = embeddedMaxMindDBASN
+}
@@ -6,3 +6,6 @@ import (
 
 //go:embed GeoLite2-City.mmdb.zst
 var EmbeddedMaxMindDBCity []byte
+
+// EmbeddedMaxMindDBASN 内置的 ASN 数据库, 仅在使用 asn_embed 构建标签时非空
+var EmbeddedMaxMindDBASN []byte
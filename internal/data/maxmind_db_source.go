@@ -0,0 +1,351 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGitHubAPI      = "https://api.github.com"
+	defaultGeoLite2Repo   = "mojolabs-id/GeoLite2-Database"
+	defaultMaxMindBaseURL = "https://download.maxmind.com/geoip/databases"
+	defaultMaxMindEdition = "GeoLite2-City"
+	defaultDBIPBaseURL    = "https://download.db-ip.com/free"
+	defaultDBIPEdition    = "city-lite"
+	defaultIPinfoLiteURL  = "https://ipinfo.io/data/ipinfo_lite.mmdb"
+)
+
+// ErrNotModified 更新源的数据库与上次安装的相同
+var ErrNotModified = errors.New("数据库未变化")
+
+// Artifact 更新源提供的数据库文件
+type Artifact struct {
+	Body         io.ReadCloser
+	Name         string // 文件名, 以 .gz / .tar.gz / .tgz 结尾时解压
+	SHA256       string // Body 原始内容的 SHA-256, 为空时不校验
+	DBType       string // 数据库类型(metadata.database_type)应包含的字符串, 为空时不检查
+	Version      string // 版本标识, 如 release tag
+	ETag         string
+	LastModified string
+}
+
+// UpdateSource 数据库更新源
+type UpdateSource interface {
+	Name() string
+	// Fetch 获取最新数据库, 调用方负责关闭 Body; 与 prev 记录的版本相同时返回 ErrNotModified
+	Fetch(ctx context.Context, hc *http.Client, prev UpdateState) (*Artifact, error)
+}
+
+// GitHubReleaseSource 从 GitHub release 下载数据库, 发布页提供校验和时校验 SHA-256
+type GitHubReleaseSource struct {
+	Repo   string // owner/name, 默认 mojolabs-id/GeoLite2-Database
+	Asset  string // 默认 GeoLite2-City.mmdb
+	Proxy  string // 下载地址前缀, 如 https://ghproxy.net/
+	APIURL string // 默认 https://api.github.com
+}
+
+// Name 更新源名称
+func (s *GitHubReleaseSource) Name() string { return "github" }
+
+// Fetch 获取最新 release 中的数据库
+func (s *GitHubReleaseSource) Fetch(ctx context.Context, hc *http.Client, prev UpdateState) (*Artifact, error) {
+	repo := cmp.Or(s.Repo, defaultGeoLite2Repo)
+	asset := cmp.Or(s.Asset, CityDBFileName)
+
+	rel, err := fetchRelease(ctx, hc, cmp.Or(s.APIURL, defaultGitHubAPI)+"/repos/"+repo+"/releases/latest")
+	if err != nil {
+		return nil, err
+	}
+	if rel.TagName != "" && rel.TagName == prev.Version {
+		return nil, ErrNotModified
+	}
+	downloadURL := rel.assetURL(asset)
+	if downloadURL == "" {
+		return nil, fmt.Errorf("未找到 %s 下载地址", asset)
+	}
+
+	// 发布页提供校验和时必须校验通过
+	var sum string
+	for _, name := range checksumAssetNames(asset) {
+		if u := rel.assetURL(name); u != "" {
+			if sum, err = fetchChecksum(ctx, hc, s.Proxy+u, asset); err != nil {
+				return nil, fmt.Errorf("获取校验和失败: %w", err)
+			}
+			break
+		}
+	}
+
+	resp, err := openURL(ctx, hc, s.Proxy+downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	a := newArtifact(resp, asset)
+	a.SHA256 = sum
+	a.DBType = strings.TrimSuffix(asset, ".mmdb")
+	a.Version = rel.TagName
+	return a, nil
+}
+
+// MaxMindSource MaxMind 官方下载接口, 需要账号 ID 及许可证密钥
+type MaxMindSource struct {
+	AccountID  string
+	LicenseKey string
+	EditionID  string // 默认 GeoLite2-City
+	BaseURL    string // 默认 https://download.maxmind.com/geoip/databases
+}
+
+// Name 更新源名称
+func (s *MaxMindSource) Name() string { return "maxmind" }
+
+// Fetch 下载 tar.gz 数据库及其 SHA-256
+func (s *MaxMindSource) Fetch(ctx context.Context, hc *http.Client, prev UpdateState) (*Artifact, error) {
+	if s.AccountID == "" || s.LicenseKey == "" {
+		return nil, fmt.Errorf("MaxMind 更新需要账号 ID 及许可证密钥")
+	}
+	edition := cmp.Or(s.EditionID, defaultMaxMindEdition)
+	base := cmp.Or(s.BaseURL, defaultMaxMindBaseURL) + "/" + url.PathEscape(edition) + "/download?suffix="
+	auth := func(req *http.Request) { req.SetBasicAuth(s.AccountID, s.LicenseKey) }
+
+	sumResp, err := openURL(ctx, hc, base+"tar.gz.sha256", auth)
+	if err != nil {
+		return nil, fmt.Errorf("获取校验和失败: %w", err)
+	}
+	content, err := io.ReadAll(io.LimitReader(sumResp.Body, 4096))
+	sumResp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("获取校验和失败: %w", err)
+	}
+	sum, err := parseChecksum(string(content), "")
+	if err != nil {
+		return nil, fmt.Errorf("获取校验和失败: %w", err)
+	}
+
+	if sum == prev.SHA256 {
+		return nil, ErrNotModified
+	}
+
+	resp, err := openURL(ctx, hc, base+"tar.gz", auth, conditional(prev))
+	if err != nil {
+		return nil, err
+	}
+	a := newArtifact(resp, edition+".tar.gz")
+	a.SHA256 = sum
+	a.DBType = edition
+	return a, nil
+}
+
+// DBIPLiteSource DB-IP Lite 免费数据库, 按月发布; 当月文件尚未发布时使用上月文件
+type DBIPLiteSource struct {
+	Edition string // city-lite(默认)、country-lite 或 asn-lite; asn-lite 只能更新 ASN 数据库
+	BaseURL string // 默认 https://download.db-ip.com/free
+
+	now func() time.Time
+}
+
+// Name 更新源名称
+func (s *DBIPLiteSource) Name() string { return "dbip" }
+
+// Fetch 下载当月(或上月)的 mmdb.gz
+func (s *DBIPLiteSource) Fetch(ctx context.Context, hc *http.Client, prev UpdateState) (*Artifact, error) {
+	edition := cmp.Or(s.Edition, defaultDBIPEdition)
+	var dbType string
+	switch edition {
+	case "city-lite":
+		dbType = "City"
+	case "country-lite":
+		dbType = "Country"
+	case "asn-lite":
+		dbType = "ASN"
+	default:
+		return nil, fmt.Errorf("未知的 DB-IP 版本: %s", edition)
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	month := now().UTC()
+
+	var lastErr error
+	for range 2 {
+		version := month.Format("2006-01")
+		if version == prev.Version {
+			return nil, ErrNotModified
+		}
+		name := fmt.Sprintf("dbip-%s-%s.mmdb.gz", edition, version)
+		resp, err := openURL(ctx, hc, cmp.Or(s.BaseURL, defaultDBIPBaseURL)+"/"+name)
+		if err == nil {
+			a := newArtifact(resp, name)
+			a.DBType = dbType
+			a.Version = version
+			return a, nil
+		}
+		lastErr = err
+		month = time.Date(month.Year(), month.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return nil, lastErr
+}
+
+// IPinfoLiteSource IPinfo Lite 数据库(国家及 ASN), 需要访问令牌; 其记录结构(country 为字符串)与 MaxMind 不同,
+// 不能替换 City 数据库(verifyMMDB 会拒绝类型变化)
+type IPinfoLiteSource struct {
+	Token string
+	URL   string // 默认 https://ipinfo.io/data/ipinfo_lite.mmdb
+}
+
+// Name 更新源名称
+func (s *IPinfoLiteSource) Name() string { return "ipinfo" }
+
+// Fetch 下载 ipinfo_lite.mmdb
+func (s *IPinfoLiteSource) Fetch(ctx context.Context, hc *http.Client, prev UpdateState) (*Artifact, error) {
+	if s.Token == "" {
+		return nil, fmt.Errorf("IPinfo 更新需要访问令牌")
+	}
+	resp, err := openURL(ctx, hc, cmp.Or(s.URL, defaultIPinfoLiteURL), func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}, conditional(prev))
+	if err != nil {
+		return nil, err
+	}
+	return newArtifact(resp, "ipinfo_lite.mmdb"), nil
+}
+
+// URLSource 从任意地址下载数据库, 地址以 .gz / .tar.gz 结尾时解压
+type URLSource struct {
+	URL         string
+	ChecksumURL string // sha256sum 格式的校验和文件, 为空时不校验
+	DBType      string // 数据库类型应包含的字符串, 为空时不检查
+}
+
+// Name 更新源名称
+func (s *URLSource) Name() string { return "url" }
+
+// Fetch 下载数据库
+func (s *URLSource) Fetch(ctx context.Context, hc *http.Client, prev UpdateState) (*Artifact, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("无效的下载地址: %q", s.URL)
+	}
+	name := path.Base(u.Path)
+
+	var sum string
+	if s.ChecksumURL != "" {
+		if sum, err = fetchChecksum(ctx, hc, s.ChecksumURL, name); err != nil {
+			return nil, fmt.Errorf("获取校验和失败: %w", err)
+		}
+		if sum == prev.SHA256 {
+			return nil, ErrNotModified
+		}
+	}
+	resp, err := openURL(ctx, hc, s.URL, conditional(prev))
+	if err != nil {
+		return nil, err
+	}
+	a := newArtifact(resp, name)
+	a.SHA256 = sum
+	a.DBType = s.DBType
+	return a, nil
+}
+
+// DirSource 从本地目录复制数据库(如 geoipupdate 的输出目录); 存在 <File>.sha256 时校验,
+// 以文件修改时间判断是否变化
+type DirSource struct {
+	Dir  string
+	File string // 默认 GeoLite2-City.mmdb
+}
+
+// Name 更新源名称
+func (s *DirSource) Name() string { return "dir" }
+
+// Fetch 打开目录中的数据库
+func (s *DirSource) Fetch(_ context.Context, _ *http.Client, prev UpdateState) (*Artifact, error) {
+	if s.Dir == "" {
+		return nil, fmt.Errorf("未指定数据库目录")
+	}
+	name := cmp.Or(s.File, CityDBFileName)
+	p := filepath.Join(s.Dir, name)
+
+	var sum string
+	if content, err := os.ReadFile(p + ".sha256"); err == nil {
+		if sum, err = parseChecksum(string(content), name); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	modified := fi.ModTime().UTC().Format(http.TimeFormat)
+	if modified == prev.LastModified && (sum == "" || sum == prev.SHA256) {
+		f.Close()
+		return nil, ErrNotModified
+	}
+	return &Artifact{Body: f, Name: name, SHA256: sum, LastModified: modified}, nil
+}
+
+// openURL 发起 GET 请求, 304 时返回 ErrNotModified, 其余非 200 状态返回错误; prepare 可修改请求(如认证)
+func openURL(ctx context.Context, hc *http.Client, url string, prepare ...func(*http.Request)) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prepare {
+		p(req)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotModified {
+			return nil, ErrNotModified
+		}
+		return nil, &httpStatusError{code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// httpStatusError 非预期的 HTTP 状态码
+type httpStatusError struct {
+	code int
+}
+
+func (e *httpStatusError) Error() string { return fmt.Sprintf("HTTP 状态码 %d", e.code) }
+
+// conditional 根据上次记录的 ETag / Last-Modified 发起条件请求
+func conditional(prev UpdateState) func(*http.Request) {
+	return func(req *http.Request) {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+}
+
+// newArtifact 由响应创建 Artifact, 记录 ETag / Last-Modified
+func newArtifact(resp *http.Response, name string) *Artifact {
+	return &Artifact{
+		Body:         resp.Body,
+		Name:         name,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
@@ -0,0 +1,185 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(b)
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzBytes(t *testing.T, name string, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "GeoLite2-City_20260101/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "GeoLite2-City_20260101/LICENSE.txt", Mode: 0644, Size: 3})
+	tw.Write([]byte("MIT"))
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(b))})
+	tw.Write(b)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return gzipBytes(t, buf.Bytes())
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// assertInstalled 使用 src 更新到新文件并检查内容
+func assertInstalled(t *testing.T, src UpdateSource, want []byte) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), CityDBFileName)
+	if _, err := UpdateDB(context.Background(), nil, src, dbPath); err != nil {
+		t.Fatalf("%s 更新失败: %v", src.Name(), err)
+	}
+	if got, _ := os.ReadFile(dbPath); !bytes.Equal(got, want) {
+		t.Errorf("%s 更新后数据库内容错误", src.Name())
+	}
+}
+
+func TestMaxMindSource(t *testing.T) {
+	mmdb := testMMDB(t)
+	archive := tarGzBytes(t, "GeoLite2-City_20260101/"+CityDBFileName, mmdb)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "42" || pass != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/GeoLite2-City/download" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("suffix") {
+		case "tar.gz":
+			w.Write(archive)
+		case "tar.gz.sha256":
+			fmt.Fprintf(w, "%s  GeoLite2-City_20260101.tar.gz\n", sha256Hex(archive))
+		}
+	}))
+	defer srv.Close()
+
+	assertInstalled(t, &MaxMindSource{AccountID: "42", LicenseKey: "key", BaseURL: srv.URL}, mmdb)
+
+	ctx := context.Background()
+	if _, err := (&MaxMindSource{AccountID: "42", LicenseKey: "bad", BaseURL: srv.URL}).Fetch(ctx, srv.Client(), UpdateState{}); err == nil {
+		t.Error("认证失败应返回错误")
+	}
+	if _, err := (&MaxMindSource{BaseURL: srv.URL}).Fetch(ctx, srv.Client(), UpdateState{}); err == nil {
+		t.Error("缺少许可证密钥应返回错误")
+	}
+}
+
+func TestDBIPLiteSource(t *testing.T) {
+	mmdb := testMMDB(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 当月文件尚未发布
+		if r.URL.Path != "/dbip-city-lite-2026-09.mmdb.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(gzipBytes(t, mmdb))
+	}))
+	defer srv.Close()
+
+	now := func() time.Time { return time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC) }
+	src := &DBIPLiteSource{BaseURL: srv.URL, now: now}
+	a, err := src.Fetch(context.Background(), srv.Client(), UpdateState{})
+	if err != nil {
+		t.Fatalf("获取失败: %v", err)
+	}
+	a.Body.Close()
+	if a.Version != "2026-09" || a.DBType != "City" {
+		t.Errorf("版本或类型错误: %s %s", a.Version, a.DBType)
+	}
+	assertInstalled(t, src, mmdb)
+
+	if _, err := (&DBIPLiteSource{Edition: "isp", BaseURL: srv.URL}).Fetch(context.Background(), srv.Client(), UpdateState{}); err == nil {
+		t.Error("未知版本应返回错误")
+	}
+}
+
+func TestIPinfoLiteSource(t *testing.T) {
+	mmdb := testMMDB(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write(mmdb)
+	}))
+	defer srv.Close()
+
+	assertInstalled(t, &IPinfoLiteSource{Token: "token", URL: srv.URL + "/ipinfo_lite.mmdb"}, mmdb)
+
+	if _, err := (&IPinfoLiteSource{Token: "bad", URL: srv.URL}).Fetch(context.Background(), srv.Client(), UpdateState{}); err == nil {
+		t.Error("令牌无效应返回错误")
+	}
+}
+
+func TestURLSource(t *testing.T) {
+	mmdb := testMMDB(t)
+	gz := gzipBytes(t, mmdb)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/city.mmdb.gz":
+			w.Write(gz)
+		case "/city.mmdb.gz.sha256":
+			fmt.Fprintf(w, "%s *city.mmdb.gz\n", sha256Hex(gz))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	assertInstalled(t, &URLSource{URL: srv.URL + "/city.mmdb.gz", ChecksumURL: srv.URL + "/city.mmdb.gz.sha256", DBType: "City"}, mmdb)
+
+	if _, err := (&URLSource{URL: "ftp://example.com/city.mmdb"}).Fetch(context.Background(), srv.Client(), UpdateState{}); err == nil {
+		t.Error("非 HTTP 地址应返回错误")
+	}
+}
+
+func TestDirSource(t *testing.T) {
+	mmdb := testMMDB(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, CityDBFileName), mmdb, 0644)
+
+	src := &DirSource{Dir: dir}
+	assertInstalled(t, src, mmdb)
+
+	// 校验和不匹配时保留原文件
+	os.WriteFile(filepath.Join(dir, CityDBFileName+".sha256"), []byte(sha256Hex(nil)+"  "+CityDBFileName), 0644)
+	a, err := src.Fetch(context.Background(), nil, UpdateState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Body.Close()
+	dbPath := filepath.Join(t.TempDir(), CityDBFileName)
+	if err := installArtifact(a, dbPath); err == nil {
+		t.Error("校验和不匹配应返回错误")
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Error("校验失败时不应生成数据库文件")
+	}
+}
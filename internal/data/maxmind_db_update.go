@@ -0,0 +1,380 @@
+package data
+
+import (
+	"archive/tar"
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+const defaultGithubProxy = "https://ghproxy.net/"
+
+// checksumAssetNames 发布页中可能存放 asset 校验和的文件名, 依次尝试
+func checksumAssetNames(asset string) []string {
+	return []string{
+		asset + ".sha256",
+		asset + ".sha256sum",
+		"sha256sums.txt",
+		"SHA256SUMS",
+		"checksums.txt",
+	}
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL 返回指定名称的下载地址
+func (r *githubRelease) assetURL(name string) string {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.BrowserDownloadURL
+		}
+	}
+	return ""
+}
+
+// UpdateStatus 数据库更新结果
+type UpdateStatus int
+
+const (
+	UpdateFailed   UpdateStatus = iota // 更新失败, 保留原文件
+	UpdateUpToDate                     // 已是最新, 未下载
+	UpdateUpdated                      // 已下载并替换
+)
+
+// String 状态描述
+func (s UpdateStatus) String() string {
+	switch s {
+	case UpdateUpToDate:
+		return "up to date"
+	case UpdateUpdated:
+		return "updated"
+	default:
+		return "failed"
+	}
+}
+
+// MarshalText 以状态描述序列化
+func (s UpdateStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UpdateState 上次更新记录, 保存在数据库旁的 <db>.state.json 中, 用于跳过未变化的下载
+type UpdateState struct {
+	Source       string    `json:"source"`
+	Version      string    `json:"version,omitempty"` // release tag 等版本标识
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
+	BuildEpoch   uint      `json:"build_epoch"`
+	CheckedAt    time.Time `json:"checked_at"`
+	UpdatedAt    time.Time `json:"updated_at,omitzero"`
+}
+
+// updateStatePath 更新记录文件路径
+func updateStatePath(dbPath string) string {
+	return dbPath + ".state.json"
+}
+
+// LoadUpdateState 读取 dbPath 的更新记录
+func LoadUpdateState(dbPath string) (UpdateState, error) {
+	var st UpdateState
+	content, err := os.ReadFile(updateStatePath(dbPath))
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(content, &st); err != nil {
+		return UpdateState{}, fmt.Errorf("解析更新记录失败: %w", err)
+	}
+	return st, nil
+}
+
+// saveUpdateState 写入更新记录
+func saveUpdateState(dbPath string, st UpdateState) error {
+	content, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(updateStatePath(dbPath), content)
+}
+
+// UpdateGeoLite2DB 从默认 GitHub release 更新 GeoLite2 数据库, 下载地址前缀取自 GITHUB_PROXY
+func UpdateGeoLite2DB(dbPath string) error {
+	_, err := UpdateDB(context.Background(), nil, DefaultUpdateSource(), dbPath)
+	return err
+}
+
+// DefaultUpdateSource 默认更新源: mojolabs-id/GeoLite2-Database release, 经 GITHUB_PROXY(默认 ghproxy.net)下载
+func DefaultUpdateSource() UpdateSource {
+	return &GitHubReleaseSource{Proxy: cmp.Or(os.Getenv("GITHUB_PROXY"), defaultGithubProxy)}
+}
+
+// UpdateDB 从 src 更新数据库; 更新源的版本、ETag 或校验和与上次记录相同时跳过下载.
+// 下载到临时文件并校验 SHA-256(若更新源提供)、数据库类型及构建时间后原子替换,
+// 任一步骤失败时保留原文件. hc 为空时使用默认客户端
+func UpdateDB(ctx context.Context, hc *http.Client, src UpdateSource, dbPath string) (UpdateStatus, error) {
+	if hc == nil {
+		hc = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	// 更新源变化或数据库被替换(构建时间不一致)时记录失效, 重新下载
+	prev, _ := LoadUpdateState(dbPath)
+	cur, ok := dbMetadata(dbPath)
+	if !ok || prev.Source != src.Name() || prev.BuildEpoch != cur.BuildEpoch {
+		prev = UpdateState{}
+	}
+
+	// 下载（网络错误或 5xx 时重试, 共 3 次; 校验失败等确定性错误不重试, 避免重复下载）
+	var err error
+	for i := range 3 {
+		var a *Artifact
+		if a, err = src.Fetch(ctx, hc, prev); err == nil {
+			err = installArtifact(a, dbPath)
+			a.Body.Close()
+			if err == nil {
+				now := time.Now()
+				st := UpdateState{
+					Source:       src.Name(),
+					Version:      a.Version,
+					ETag:         a.ETag,
+					LastModified: a.LastModified,
+					SHA256:       a.SHA256,
+					CheckedAt:    now,
+					UpdatedAt:    now,
+				}
+				if md, ok := dbMetadata(dbPath); ok {
+					st.BuildEpoch = md.BuildEpoch
+				}
+				if err := saveUpdateState(dbPath, st); err != nil {
+					slog.Warn("保存更新记录失败", "error", err)
+				}
+				slog.Info(fmt.Sprintf("%s 更新完成", filepath.Base(dbPath)), "source", src.Name(), "version", a.Version)
+				return UpdateUpdated, nil
+			}
+		}
+		if errors.Is(err, ErrNotModified) {
+			prev.CheckedAt = time.Now()
+			if err := saveUpdateState(dbPath, prev); err != nil {
+				slog.Warn("保存更新记录失败", "error", err)
+			}
+			slog.Info(fmt.Sprintf("%s 已是最新", filepath.Base(dbPath)), "source", src.Name(), "version", prev.Version)
+			return UpdateUpToDate, nil
+		}
+		slog.Warn(fmt.Sprintf("%s 更新失败 (%d/3)", src.Name(), i+1), "error", err)
+		if ctx.Err() != nil || !retryable(err) {
+			break
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return UpdateFailed, fmt.Errorf("更新失败，保留原文件: %w", err)
+}
+
+// retryable 是否为可重试的错误: 网络错误、响应体中断或 5xx 状态码
+func retryable(err error) bool {
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.code >= http.StatusInternalServerError
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// dbMetadata 读取数据库的元数据, 文件不存在或无效时返回 false
+func dbMetadata(path string) (*maxminddb.Metadata, bool) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer db.Close()
+	md := db.Metadata
+	return &md, true
+}
+
+// mmdbKind 根据 database_type 归类, 与 ipinfo.DetectDBType 的识别顺序一致
+func mmdbKind(databaseType string) string {
+	for _, kind := range []string{"Anonymous-IP", "ASN", "City", "Country"} {
+		if strings.Contains(databaseType, kind) {
+			return kind
+		}
+	}
+	return "custom"
+}
+
+// fetchRelease 获取 GitHub release 信息
+func fetchRelease(ctx context.Context, hc *http.Client, apiURL string) (*githubRelease, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取 release 信息失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API: %w", &httpStatusError{code: resp.StatusCode})
+	}
+
+	var rel githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("解析 release JSON 失败: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body) // 确保读完
+	return &rel, nil
+}
+
+// fetchChecksum 下载校验和文件并取出 name 对应的 SHA-256
+func fetchChecksum(ctx context.Context, hc *http.Client, url, name string) (string, error) {
+	content, err := fetchText(ctx, hc, url)
+	if err != nil {
+		return "", err
+	}
+	return parseChecksum(content, name)
+}
+
+// parseChecksum 解析 sha256sum 格式("<hex>  <name>" 或 "<hex> *<name>"), 仅含一个哈希或 name 为空时取第一个
+func parseChecksum(content, name string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+			continue
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil {
+			continue
+		}
+		if name == "" || len(fields) == 1 || filepath.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("未找到 %s 的 SHA-256", name)
+}
+
+// installArtifact 解压到同目录的临时文件, 校验后原子替换 dbPath
+func installArtifact(a *Artifact, dbPath string) error {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), filepath.Base(dbPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// 校验和针对原始内容(压缩包)计算
+	h := sha256.New()
+	raw := io.TeeReader(a.Body, h)
+	if err := extractMMDB(tmp, raw, a.Name); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if a.SHA256 != "" {
+		if got := hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(a.SHA256) {
+			return fmt.Errorf("SHA-256 不匹配: %s != %s", got, a.SHA256)
+		}
+	}
+
+	cur, _ := dbMetadata(dbPath)
+	if err := verifyMMDB(tmp.Name(), a.DBType, cur); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dbPath)
+}
+
+// extractMMDB 按文件名解压: .tar.gz / .tgz 取包内第一个 .mmdb, .gz 直接解压, 其余原样复制
+func extractMMDB(dst io.Writer, src io.Reader, name string) error {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("解压失败: %w", err)
+		}
+		defer gz.Close()
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return fmt.Errorf("压缩包中没有 .mmdb 文件")
+			}
+			if err != nil {
+				return fmt.Errorf("解压失败: %w", err)
+			}
+			if hdr.Typeflag == tar.TypeReg && strings.HasSuffix(hdr.Name, ".mmdb") {
+				_, err = io.Copy(dst, tr)
+				return err
+			}
+		}
+	case strings.HasSuffix(name, ".gz"):
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("解压失败: %w", err)
+		}
+		defer gz.Close()
+		_, err = io.Copy(dst, gz)
+		return err
+	default:
+		_, err := io.Copy(dst, src)
+		return err
+	}
+}
+
+// verifyMMDB 试打开数据库并检查类型及构建时间; cur 为当前数据库的元数据(可为空),
+// 新数据库的类型须与之相同(如 City 不能被 ASN 或 IPinfo Lite 替换), 且构建时间不早于当前数据库
+func verifyMMDB(path, wantType string, cur *maxminddb.Metadata) error {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return fmt.Errorf("数据库无效: %w", err)
+	}
+	defer db.Close()
+
+	md := db.Metadata
+	if !strings.Contains(md.DatabaseType, wantType) {
+		return fmt.Errorf("数据库类型 %q 不是 %s", md.DatabaseType, wantType)
+	}
+	if md.NodeCount == 0 {
+		return errors.New("数据库为空")
+	}
+	if cur == nil {
+		return nil
+	}
+	if got, want := mmdbKind(md.DatabaseType), mmdbKind(cur.DatabaseType); got != want {
+		return fmt.Errorf("数据库类型 %q 与当前数据库 %q 不同", md.DatabaseType, cur.DatabaseType)
+	}
+	if md.BuildEpoch < cur.BuildEpoch {
+		return fmt.Errorf("数据库构建时间 %s 早于当前数据库 %s",
+			time.Unix(int64(md.BuildEpoch), 0).UTC().Format(time.DateOnly),
+			time.Unix(int64(cur.BuildEpoch), 0).UTC().Format(time.DateOnly))
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// testMMDB 返回解压后的内置 City 数据库
+func testMMDB(t *testing.T) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, CityDBFileName)
+	if err := ensureMMDBFile(EmbeddedMaxMindDBCity, dir, path); err != nil {
+		t.Fatal(err)
+	}
+	mmdb, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mmdb
+}
+
+// newReleaseServer 模拟 GitHub release API 及下载地址, sum 为空时不发布校验和; downloads 记录下载次数
+func newReleaseServer(t *testing.T, mmdb []byte, sum string, downloads *int) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + defaultGeoLite2Repo + "/releases/latest":
+			assets := fmt.Sprintf(`{"name":%q,"browser_download_url":%q}`, CityDBFileName, srv.URL+"/db")
+			if sum != "" {
+				assets += fmt.Sprintf(`,{"name":%q,"browser_download_url":%q}`, CityDBFileName+".sha256", srv.URL+"/sum")
+			}
+			fmt.Fprintf(w, `{"tag_name":"v1","assets":[%s]}`, assets)
+		case "/db":
+			if downloads != nil {
+				*downloads++
+			}
+			w.Write(mmdb)
+		case "/sum":
+			fmt.Fprintf(w, "%s  %s\n", sum, CityDBFileName)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpdateDBGitHub(t *testing.T) {
+	dir := t.TempDir()
+	mmdb := testMMDB(t)
+	sum := sha256.Sum256(mmdb)
+	ctx := context.Background()
+
+	dbPath := filepath.Join(dir, "live", CityDBFileName)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := []byte("old database")
+	os.WriteFile(dbPath, old, 0644)
+
+	// 校验和不匹配时保留原文件
+	bad := newReleaseServer(t, mmdb, hex.EncodeToString(make([]byte, sha256.Size)), nil)
+	if _, err := UpdateDB(ctx, nil, &GitHubReleaseSource{APIURL: bad.URL}, dbPath); err == nil {
+		t.Error("校验和不匹配应返回错误")
+	}
+	// 下载到 HTML 错误页时保留原文件, 校验失败不重试
+	htmlDownloads := 0
+	html := newReleaseServer(t, []byte("<html>rate limited</html>"), "", &htmlDownloads)
+	if _, err := UpdateDB(ctx, nil, &GitHubReleaseSource{APIURL: html.URL}, dbPath); err == nil {
+		t.Error("无效数据库应返回错误")
+	}
+	if htmlDownloads != 1 {
+		t.Errorf("校验失败不应重试, 下载次数 %d", htmlDownloads)
+	}
+	if got, _ := os.ReadFile(dbPath); !bytes.Equal(got, old) {
+		t.Error("更新失败时不应替换原文件")
+	}
+
+	downloads := 0
+	ok := newReleaseServer(t, mmdb, hex.EncodeToString(sum[:]), &downloads)
+	if _, err := UpdateDB(ctx, nil, &GitHubReleaseSource{APIURL: ok.URL}, dbPath); err != nil {
+		t.Fatalf("更新失败: %v", err)
+	}
+	if got, _ := os.ReadFile(dbPath); !bytes.Equal(got, mmdb) {
+		t.Error("更新后数据库内容错误")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dbPath))
+	if len(entries) != 2 {
+		t.Errorf("应仅有数据库及更新记录, 不应残留临时文件: %v", entries)
+	}
+
+	st, err := LoadUpdateState(dbPath)
+	if err != nil {
+		t.Fatalf("读取更新记录失败: %v", err)
+	}
+	if st.Source != "github" || st.Version != "v1" || st.BuildEpoch == 0 || st.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("更新记录错误: %+v", st)
+	}
+
+	// release tag 未变化时不下载
+	status, err := UpdateDB(ctx, nil, &GitHubReleaseSource{APIURL: ok.URL}, dbPath)
+	if err != nil || status != UpdateUpToDate {
+		t.Errorf("未变化时应返回 up to date: %v %v", status, err)
+	}
+	if downloads != 1 {
+		t.Errorf("未变化时不应下载, 下载次数 %d", downloads)
+	}
+}
+
+func TestUpdateDBConditional(t *testing.T) {
+	mmdb := testMMDB(t)
+	const etag = `"v1"`
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Write(mmdb)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), CityDBFileName)
+	src := &URLSource{URL: srv.URL + "/" + CityDBFileName}
+
+	wants := []UpdateStatus{UpdateUpdated, UpdateUpToDate}
+	for _, want := range wants {
+		status, err := UpdateDB(ctx, nil, src, dbPath)
+		if err != nil || status != want {
+			t.Errorf("UpdateDB() = %v, %v, 期望 %v", status, err, want)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("ETag 未变化时不应下载, 下载次数 %d", downloads)
+	}
+
+	// 更新源变化时记录失效, 重新下载
+	st, _ := LoadUpdateState(dbPath)
+	st.Source = "github"
+	if err := saveUpdateState(dbPath, st); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := UpdateDB(ctx, nil, src, dbPath); err != nil || status != UpdateUpdated {
+		t.Errorf("更新源变化后应重新下载: %v %v", status, err)
+	}
+
+	if UpdateFailed.String() != "failed" || UpdateUpToDate.String() != "up to date" || UpdateUpdated.String() != "updated" {
+		t.Error("UpdateStatus 描述错误")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&httpStatusError{code: http.StatusBadGateway}, true},
+		{fmt.Errorf("GitHub API: %w", &httpStatusError{code: http.StatusServiceUnavailable}), true},
+		{&httpStatusError{code: http.StatusNotFound}, false},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("解压失败: %w", io.ErrUnexpectedEOF), true},
+		{errors.New("SHA-256 不匹配"), false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, 期望 %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	h := "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{h, h},
+		{h + "  " + CityDBFileName, h},
+		{h + " *dist/" + CityDBFileName, h},
+		{"0000  other.mmdb\n" + h + "  " + CityDBFileName, h},
+		{h + "  GeoLite2-ASN.mmdb", ""},
+		{"<html></html>", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseChecksum(tt.content, CityDBFileName)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("parseChecksum(%q) 应返回错误", tt.content)
+			}
+			continue
+		}
+		if err != nil || got != strings.ToLower(tt.want) {
+			t.Errorf("parseChecksum(%q) = %q, %v", tt.content, got, err)
+		}
+	}
+}
+
+func TestVerifyMMDB(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, CityDBFileName)
+	if err := ensureMMDBFile(EmbeddedMaxMindDBCity, dir, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyMMDB(path, "City", nil); err != nil {
+		t.Errorf("有效数据库校验失败: %v", err)
+	}
+	if err := verifyMMDB(path, "ASN", nil); err == nil {
+		t.Error("数据库类型不符应返回错误")
+	}
+	cur, ok := dbMetadata(path)
+	if !ok {
+		t.Fatal("读取元数据失败")
+	}
+	if err := verifyMMDB(path, "", cur); err != nil {
+		t.Errorf("同类型数据库校验失败: %v", err)
+	}
+	// 类型与当前数据库不同时拒绝, 避免 City 与 ASN、IPinfo Lite 等数据库互相替换
+	for _, typ := range []string{"DBIP-ASN-Lite", "ipinfo ipinfo_lite.mmdb"} {
+		if err := verifyMMDB(path, "", &maxminddb.Metadata{DatabaseType: typ}); err == nil {
+			t.Errorf("当前数据库为 %s 时应拒绝类型不同的数据库", typ)
+		}
+	}
+	newer := *cur
+	newer.BuildEpoch = 1 << 40
+	if err := verifyMMDB(path, "City", &newer); err == nil {
+		t.Error("构建时间早于当前数据库应返回错误")
+	}
+}
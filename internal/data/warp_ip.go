@@ -0,0 +1,13 @@
+package data
+
+import (
+	_ "embed"
+)
+
+//go:embed warp_egress.txt
+var embeddedWARPEgress string
+
+// WARPEgressRanges 返回内置 WARP 出口网段文本, 格式同 CDNRanges
+func WARPEgressRanges() string {
+	return embeddedWARPEgress
+}
@@ -3,6 +3,8 @@ package resolver
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,28 +14,98 @@ import (
 	"github.com/sinspired/checkip/pkg/ipinfo"
 )
 
-// NewResolver 创建一个新的 Resolver 实例
-func NewResolver(cfCdnRanges map[string][]*net.IPNet, geoDB *maxminddb.Reader) *Resolver {
-	cli, _ := ipinfo.New(
+// resolveTimeout 单次解析的总超时, ctx 到期后会中止进行中的请求
+const resolveTimeout = 15 * time.Second
+
+// NewResolver 创建一个新的 Resolver 实例, opts 追加到内部 ipinfo 客户端的设置中;
+// cfCdnRanges 非空时替换内置 Cloudflare 网段, 为空时使用内置网段(随运行时更新);
+// geoDB 非空时替换内置数据库, 需要热替换时传入 nil 并使用 ipinfo.WithReloadableDB;
+// 客户端初始化失败(如网段文件不存在或无法解析)时返回错误
+func NewResolver(cfCdnRanges map[string][]*net.IPNet, geoDB *maxminddb.Reader, opts ...ipinfo.Option) (*Resolver, error) {
+	base := []ipinfo.Option{
 		ipinfo.WithHttpClient(&http.Client{Timeout: 10 * time.Second}),
-	)
+	}
+	if geoDB != nil {
+		base = append(base, ipinfo.WithDBReader(geoDB))
+	}
+	if p := ipinfo.CDNProviderFromIPNets(ipinfo.CDNCloudflare, cfCdnRanges); len(p.Prefixes) > 0 {
+		base = append(base, ipinfo.WithCDNProvider(p))
+	}
+	cli, err := ipinfo.New(append(base, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 ipinfo 客户端失败: %w", err)
+	}
 	return &Resolver{
 		cli:        cli,
-		geoDB:      geoDB,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Reload 重新加载 CDN 网段文件
+func (r *Resolver) Reload() error {
+	return r.cli.ReloadCDNRanges()
+}
+
+// ReloadDB 重新加载可热替换的 MaxMind 数据库, 进行中的查询继续使用旧数据库
+func (r *Resolver) ReloadDB() error {
+	return r.cli.ReloadDatabases()
+}
+
+// Meta 返回已加载数据库的元数据及客户端当前生效的 Cloudflare 网段版本、数量,
+// 网段包含传入的网段、CF_CIDR_PATH 等追加的网段文件及重新加载的结果
+func (r *Resolver) Meta() *MetaResult {
+	var ranges CFRangesMeta
+	if p, ok := r.cli.CDNProvider(ipinfo.CDNCloudflare); ok {
+		h := sha256.New()
+		for _, prefix := range p.Prefixes {
+			if prefix.Addr().Is4() {
+				ranges.IPv4++
+			} else {
+				ranges.IPv6++
+			}
+			fmt.Fprintln(h, prefix)
+		}
+		ranges.Version = hex.EncodeToString(h.Sum(nil)[:6])
+	}
+	return &MetaResult{
+		Databases:        r.cli.DatabaseMeta(),
+		CloudflareRanges: ranges,
 	}
 }
 
-// 填充 ResolveResult 公共逻辑
-func fillResult(ip string, isCDN bool, loc, tag string, data *ipinfo.IPData) *ResolveResult {
+// Close 停止网段文件检查并释放内部客户端资源
+func (r *Resolver) Close() error {
+	return r.cli.Close()
+}
+
+// 填充 ResolveResult 公共逻辑, analyzed 为当前出口的分析结果, 可为空
+func fillResult(ip string, isCDN bool, data *ipinfo.IPData, analyzed *ipinfo.AnalyzeResult) *ResolveResult {
+	var loc, tag, category, colo string
+	var reasons []string
+	var probes []ipinfo.ProbeResult
+	if analyzed != nil {
+		loc, tag, category, colo = analyzed.Loc, analyzed.Tag, analyzed.Category.String(), analyzed.Colo.Code
+		reasons, probes = analyzed.Reasons, analyzed.Probes
+	}
 	return &ResolveResult{
 		Tag:           tag,
+		Category:      category,
+		Reasons:       reasons,
+		CFColo:        colo,
+		Probes:        probes,
 		IsCDN:         isCDN,
+		CDN:           data.CDN,
+		CDNPrefix:     data.CDNPrefix,
+		WARP:          string(data.WARP),
 		IP:            ip,
 		CountryCode:   data.CountryCode,
 		CountryName:   data.CountryName,
 		ContinentCode: data.ContinentCode,
 		City:          data.City,
+
+		ASN:            data.ASN,
+		ASOrganization: data.ASOrganization,
+		Network:        data.Network,
 		LocationInfo: LocationInfo{
 			Location:  loc,
 			TimeZone:  data.TimeZone,
@@ -50,54 +122,55 @@ func fillResult(ip string, isCDN bool, loc, tag string, data *ipinfo.IPData) *Re
 
 // Resolve 检查指定的 IP 地址
 func (r *Resolver) Resolve(ip string) (*ResolveResult, error) {
+	return r.ResolveContext(context.Background(), ip)
+}
+
+// ResolveContext 检查指定的 IP 地址, 仅查询本地 CDN 网段及 MaxMind 数据库, 不发起网络请求
+func (r *Resolver) ResolveContext(_ context.Context, ip string) (*ResolveResult, error) {
 	ipData := ipinfo.CreateIPDataFromIP(ip)
 
 	// 检查是否为 CDN
 	isCDN := r.cli.CheckCDN(ipData)
 
 	// 获取地理位置信息
-	if r.geoDB != nil {
-		if _, err := r.cli.LookupGeoIPDataWithMMDB(ipData); err != nil {
-			return nil, err
-		}
+	if _, err := r.cli.LookupGeoIPDataWithMMDB(ipData); err != nil {
+		return nil, err
 	}
 
-	// 获取代理信息（仅用于当前 IP，不用于指定 IP）
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	loc, _, tag, _ := r.cli.GetAnalyzed(ctx, "", "")
-
-	return fillResult(ip, isCDN, loc, tag, ipData), nil
+	// 出口分析及探测仅适用于当前 IP, 指定 IP 只返回 CDN 及数据库信息
+	return fillResult(ip, isCDN, ipData, nil), nil
 }
 
 // GetCurrentIPInfo 获取当前 IP 的地理位置信息
 func (r *Resolver) GetCurrentIPInfo() (*ResolveResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return r.GetCurrentIPInfoContext(context.Background())
+}
+
+// GetCurrentIPInfoContext 获取当前 IP 的地理位置信息, ctx 取消时中止进行中的请求
+func (r *Resolver) GetCurrentIPInfoContext(ctx context.Context) (*ResolveResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
 	defer cancel()
 
-	geoData, err := r.cli.GetGeoIPData(ctx)
+	// 一次请求同时获取地理位置与代理信息
+	analyzed, err := r.cli.Analyze(ctx, "", "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current IP info: %w", err)
 	}
-
-	// 确定 IP 地址
-	ip := geoData.IPv4
-	if ip == "" {
-		ip = geoData.IPv6
-	}
-	if ip == "" {
+	if analyzed.IP == "" {
 		return nil, fmt.Errorf("no valid IP address found")
 	}
 
-	// 获取代理信息
-	loc, _, tag, _ := r.cli.GetAnalyzed(ctx, "", "")
-
-	return fillResult(ip, geoData.IsCDN, loc, tag, &geoData), nil
+	return fillResult(analyzed.IP, analyzed.IPData.IsCDN, &analyzed.IPData, analyzed), nil
 }
 
 // GetCurrentIP 仅获取当前 IP 地址
 func (r *Resolver) GetCurrentIP() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return r.GetCurrentIPContext(context.Background())
+}
+
+// GetCurrentIPContext 仅获取当前 IP 地址, ctx 取消时中止进行中的请求
+func (r *Resolver) GetCurrentIPContext(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
 	defer cancel()
 
 	geoData, err := r.cli.GetGeoIPData(ctx)
@@ -3,7 +3,6 @@ package resolver
 import (
 	"net/http"
 
-	"github.com/oschwald/maxminddb-golang/v2"
 	"github.com/sinspired/checkip/pkg/ipinfo"
 )
 
@@ -11,7 +10,6 @@ import (
 type Resolver struct {
 	cli        *ipinfo.Client
 	httpClient *http.Client
-	geoDB      *maxminddb.Reader
 }
 
 // ResolveResult 表示检查结果
@@ -22,11 +20,24 @@ type ResolveResult struct {
 	ContinentCode string `json:"continent_code"`
 	City          string `json:"city"`
 
+	ASN            uint   `json:"asn,omitempty"`
+	ASOrganization string `json:"as_organization,omitempty"`
+	Network        string `json:"network,omitempty"`
+
 	RegionInfo   RegionInfo   `json:"region_info"`
 	LocationInfo LocationInfo `json:"location_info"`
 
-	IsCDN bool   `json:"is_cdn"`
-	Tag   string `json:"tag,omitempty"`
+	IsCDN     bool   `json:"is_cdn"`
+	CDN       string `json:"cdn,omitempty"`
+	CDNPrefix string `json:"cdn_prefix,omitempty"`
+	WARP      string `json:"warp,omitempty"` // warp / warp+ / zero_trust
+	Tag       string `json:"tag,omitempty"`
+
+	Category string   `json:"category,omitempty"` // 节点分析类别, 如 cf_same_country
+	Reasons  []string `json:"reasons,omitempty"`  // 分类依据
+	CFColo   string   `json:"cf_colo,omitempty"`  // 服务的 Cloudflare 数据中心
+
+	Probes []ipinfo.ProbeResult `json:"probes,omitempty"` // 服务可达性探测结果
 }
 
 type RegionInfo struct {
@@ -42,3 +53,16 @@ type LocationInfo struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
+
+// MetaResult 服务使用的数据库及网段信息
+type MetaResult struct {
+	Databases        []ipinfo.DBMeta `json:"databases"`
+	CloudflareRanges CFRangesMeta    `json:"cloudflare_ranges"`
+}
+
+// CFRangesMeta 当前生效的 Cloudflare 网段
+type CFRangesMeta struct {
+	Version string `json:"version"` // 网段内容的 SHA-256 前 12 位
+	IPv4    int    `json:"ipv4"`
+	IPv6    int    `json:"ipv6"`
+}
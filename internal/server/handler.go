@@ -22,11 +22,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// 处理不同的路由
 	switch {
+	case path == "meta":
+		// /api/meta - 数据库元数据及 Cloudflare 网段信息
+		json.NewEncoder(w).Encode(h.Resolver.Meta())
 	case path == "" || path == "ip":
 		// /api 或 /api/ip - 获取当前 IP
 		if path == "" {
 			// /api - 获取当前 IP 的完整信息
-			res, err := h.Resolver.GetCurrentIPInfo()
+			res, err := h.Resolver.GetCurrentIPInfoContext(r.Context())
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -34,7 +37,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(res)
 		} else {
 			// /api/ip - 仅返回 IP 地址
-			ip, err := h.Resolver.GetCurrentIP()
+			ip, err := h.Resolver.GetCurrentIPContext(r.Context())
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -64,7 +67,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		res, err := h.Resolver.Resolve(targetIP)
+		res, err := h.Resolver.ResolveContext(r.Context(), targetIP)
 		if err != nil {
 			// 所有错误都返回 400 Bad Request，避免 500
 			http.Error(w, err.Error(), http.StatusBadRequest)
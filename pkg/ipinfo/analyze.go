@@ -3,9 +3,65 @@ package ipinfo
 import (
 	"context"
 	"fmt"
+	"log/slog"
 )
 
-// GetAnalyzed 获取出口 IP 地址和地理位置信息并分析 CDN 信息, 收到 ctx 取消信号时，会加速进行获取;
+// Category 节点分析类别
+type Category int
+
+const (
+	CategoryNoCF               Category = iota // 出口未经 Cloudflare 代理
+	CategoryCFSameCountry                      // 经 Cloudflare 代理, 出口与 CF 节点识别的国家相同
+	CategoryCFDifferentCountry                 // 经 Cloudflare 代理, 出口与 CF 节点识别的国家不同
+	CategoryBadCFNode                          // 经 Cloudflare 代理, 但无法访问 Cloudflare
+	CategoryLocalISP                           // 中国大陆本地网络, 不检测 Cloudflare 代理
+	CategoryWARP                               // Cloudflare WARP / Zero Trust 出口
+)
+
+var categoryNames = map[Category]string{
+	CategoryNoCF:               "no_cf",
+	CategoryCFSameCountry:      "cf_same_country",
+	CategoryCFDifferentCountry: "cf_different_country",
+	CategoryBadCFNode:          "bad_cf_node",
+	CategoryLocalISP:           "local_isp",
+	CategoryWARP:               "warp",
+}
+
+func (c Category) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Category(%d)", int(c))
+}
+
+// MarshalText 以名称形式序列化
+func (c Category) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// AnalyzeResult 节点分析结果
+type AnalyzeResult struct {
+	IPData IPData // 出口 IP 信息, WARP 字段已根据 trace 修正
+	IP     string // 出口 IP, 优先 IPv4
+	Loc    string // 实际浏览网站识别的位置, 本地网络时为大洲代码
+
+	Trace     CFTrace // Cloudflare trace, 未请求 trace 时仅包含传入的 loc/ip
+	Colo      Colo    // 服务的 Cloudflare 数据中心
+	IsCFProxy bool    // 是否经 Cloudflare 代理
+
+	Category Category
+	Reasons  []string // 分类依据
+	Tag      string
+
+	Probes []ProbeResult // WithProbes 指定的服务可达性探测结果
+}
+
+// CFLoc 使用 CF CDN 服务的网站识别的位置
+func (r *AnalyzeResult) CFLoc() string {
+	return r.Trace.Loc
+}
+
+// GetAnalyzed 获取出口 IP 地址和地理位置信息并分析 CDN 信息, 收到 ctx 取消信号时，会中止进行中的请求;
 // countryCode_tag examples:
 //
 // - BadCFNode: HK⁻¹
@@ -16,58 +72,151 @@ import (
 //
 // - NodeWithoutCF: HK²
 //
+// - WARPNode: HK³, WARP+: HK³⁺, Zero Trust: HK³ᶻ
+//
+// - 启用 WithColoTag 时 Cloudflare 标签附加数据中心代码: HK¹-US⁰@SJC
+//
+// - 标签格式可通过 WithTagFormatter 自定义, 默认为 DefaultTagTemplate
+//
 // - 前两位字母是实际浏览网站识别的位置, -US⁰为使用CF CDN服务的网站识别的位置, 比如GPT, X等
 func (c *Client) GetAnalyzed(ctx context.Context, cfLoc string, cfIP string) (loc string, ip string, countryCode_tag string, err error) {
+	r, err := c.Analyze(ctx, cfLoc, cfIP)
+	if err != nil {
+		return "", "", "", err
+	}
+	return r.Loc, r.IP, r.Tag, nil
+}
+
+// Analyze 获取出口 IP 地址和地理位置信息并分析 CDN 信息, 返回结构化结果;
+// cfLoc 为空时请求 /cdn-cgi/trace, 否则使用传入的 cfLoc/cfIP; 指定 WithProbes 时同时执行服务探测
+func (c *Client) Analyze(ctx context.Context, cfLoc string, cfIP string) (*AnalyzeResult, error) {
+	// 探测与出口检测并发执行
+	var probes chan []ProbeResult
+	if len(c.probes) > 0 {
+		probes = make(chan []ProbeResult, 1)
+		go func() {
+			probes <- c.RunProbes(ctx, c.probes...)
+		}()
+	}
+
 	ipData, err := c.GetGeoIPData(ctx)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to get IP info data: %w", err)
+		return nil, fmt.Errorf("failed to get IP info data: %w", err)
 	}
+	r := c.analyzeIPData(ctx, ipData, cfLoc, cfIP)
+	if probes != nil {
+		r.Probes = <-probes
+		// 标签模板可使用探测结果
+		r.Tag = c.formatTag(r)
+	}
+	return r, nil
+}
 
-	ip = ipData.IPv4
-	if ip == "" {
-		ip = ipData.IPv6
+// analyzeIPData 根据出口信息及 Cloudflare trace 分类
+func (c *Client) analyzeIPData(ctx context.Context, ipData IPData, cfLoc string, cfIP string) *AnalyzeResult {
+	r := &AnalyzeResult{IP: ipData.IPv4}
+	if r.IP == "" {
+		r.IP = ipData.IPv6
 	}
 
 	// CN 不需要判断 CF Proxy
 	if ipData.CountryCode == "CN" {
-		return ipData.ContinentCode, ip, "Local ISP", nil
+		r.IPData, r.Loc = ipData, ipData.ContinentCode
+		r.Category = CategoryLocalISP
+		r.Reasons = append(r.Reasons, "出口位于中国大陆, 不检测 Cloudflare 代理")
+		r.Tag = c.formatTag(r)
+		return r
 	}
 
-	if !ipData.IsCDN {
-		countryCode_tag = ipData.CountryCode + "²"
-		return ipData.CountryCode, ip, countryCode_tag, nil
+	r.Loc = ipData.CountryCode
+	if ipData.CDN != CDNCloudflare {
+		r.IPData, r.Category = ipData, CategoryNoCF
+		if ipData.CDN != "" {
+			r.Reasons = append(r.Reasons, fmt.Sprintf("出口 IP 属于 %s CDN, 不属于 Cloudflare", ipData.CDN))
+		} else {
+			r.Reasons = append(r.Reasons, "出口 IP 不在 Cloudflare 网段内")
+		}
+		r.Tag = c.formatTag(r)
+		return r
 	}
 
-	cfProxyInfo := c.GetCfProxyInfo(&ipData, cfLoc, cfIP)
-	if cfProxyInfo.isCFProxy {
-		if cfProxyInfo.cfLoc == "" {
-			if !c.CheckCloudflareQuick() {
-				countryCode_tag = cfProxyInfo.exitLoc + "⁻¹"
-			} else {
-				countryCode_tag = cfProxyInfo.exitLoc + "¹" + "-" + "🏴‍☠️" + "⁰"
-			}
-
-		} else if cfProxyInfo.exitLoc == cfProxyInfo.cfLoc {
-			countryCode_tag = cfProxyInfo.exitLoc + "¹⁺"
+	cfProxyInfo, trace := c.cfProxyInfo(ctx, &ipData, cfLoc, cfIP)
+	r.IPData, r.Trace, r.Colo, r.IsCFProxy = ipData, trace, cfProxyInfo.colo, cfProxyInfo.isCFProxy
+
+	switch {
+	case cfProxyInfo.warp != WARPOff:
+		r.Category = CategoryWARP
+		if trace.WARP != "" {
+			r.Reasons = append(r.Reasons, fmt.Sprintf("trace 显示 warp=%s, gateway=%s", trace.WARP, trace.Gateway))
 		} else {
-			countryCode_tag = cfProxyInfo.exitLoc + "¹" + "-" + cfProxyInfo.cfLoc + "⁰"
+			r.Reasons = append(r.Reasons, "出口 IP 位于 WARP 出口网段")
 		}
-	} else {
-		countryCode_tag = cfProxyInfo.exitLoc + "²"
+	case !cfProxyInfo.isCFProxy:
+		r.Category = CategoryNoCF
+		r.Reasons = append(r.Reasons, "出口 IP 与 Cloudflare 识别的 IP 一致, 未经 Cloudflare 代理")
+	case cfProxyInfo.cfLoc == "":
+		if !c.CheckCloudflareQuickContext(ctx) {
+			r.Category = CategoryBadCFNode
+			r.Reasons = append(r.Reasons, "未获取到 Cloudflare trace, 且 Cloudflare 不可达")
+		} else {
+			r.Category = CategoryCFDifferentCountry
+			r.Reasons = append(r.Reasons, "未获取到 Cloudflare trace, 但 Cloudflare 可达")
+		}
+	case cfProxyInfo.exitLoc == cfProxyInfo.cfLoc:
+		r.Category = CategoryCFSameCountry
+		r.Reasons = append(r.Reasons, fmt.Sprintf("出口与 Cloudflare 识别的位置均为 %s", cfProxyInfo.cfLoc))
+	default:
+		r.Category = CategoryCFDifferentCountry
+		r.Reasons = append(r.Reasons, fmt.Sprintf("出口位于 %s, Cloudflare 识别为 %s", cfProxyInfo.exitLoc, cfProxyInfo.cfLoc))
+	}
+
+	if r.Colo.CountryCode != "" && r.Colo.CountryCode != cfProxyInfo.cfLoc {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("Cloudflare 数据中心 %s(%s) 位于 %s", r.Colo.Code, r.Colo.City, r.Colo.CountryCode))
+	}
+	r.Tag = c.formatTag(r)
+	return r
+}
+
+// formatTag 使用指定的标签模板生成标签, 失败时回退到内置模板
+func (c *Client) formatTag(r *AnalyzeResult) string {
+	f := c.tagFormatter
+	if f == nil {
+		f = defaultTagFormatter(c.coloTag)
+	}
+	tag, err := f.Format(r)
+	if err == nil {
+		return tag
 	}
-	return cfProxyInfo.exitLoc, ip, countryCode_tag, nil
+	slog.Warn("标签模板执行失败, 使用内置模板", "error", err)
+	tag, _ = defaultTagFormatter(c.coloTag).Format(r)
+	return tag
 }
 
 // GetCfProxyInfo 获取 /cdn-cgi/trace 获取的 CDN 节点位置
 func (c *Client) GetCfProxyInfo(info *IPData, cfLoc string, cfIP string) (cfProxyInfo CFProxyInfo) {
-	cfRelayLoc, cfRelayIP := cfLoc, cfIP
+	return c.GetCfProxyInfoContext(context.Background(), info, cfLoc, cfIP)
+}
+
+// GetCfProxyInfoContext 获取 /cdn-cgi/trace 获取的 CDN 节点位置, ctx 取消时立即返回;
+// 同时根据 trace 的 warp=/gateway= 字段更新 info.WARP, 未请求 trace 时仅依据 WARP 出口网段判断
+func (c *Client) GetCfProxyInfoContext(ctx context.Context, info *IPData, cfLoc string, cfIP string) (cfProxyInfo CFProxyInfo) {
+	cfProxyInfo, _ = c.cfProxyInfo(ctx, info, cfLoc, cfIP)
+	return cfProxyInfo
+}
+
+// cfProxyInfo 同 GetCfProxyInfoContext, 同时返回使用的 trace
+func (c *Client) cfProxyInfo(ctx context.Context, info *IPData, cfLoc string, cfIP string) (cfProxyInfo CFProxyInfo, trace CFTrace) {
+	trace = CFTrace{Loc: cfLoc, IP: cfIP}
 	if cfLoc == "" {
-		cfRelayLoc, cfRelayIP = c.GetCFTrace()
+		trace, _ = c.GetCFTraceResult(ctx)
+		info.WARP = ClassifyWARP(info, trace)
+		cfProxyInfo.colo = coloFromTrace(trace)
 	}
+	cfProxyInfo.warp = info.WARP
 
-	cfProxyInfo.isCFProxy = info.IsCDN && (info.IPv4 != cfRelayIP || info.IPv6 != "")
+	cfProxyInfo.isCFProxy = info.CDN == CDNCloudflare && (info.IPv4 != trace.IP || info.IPv6 != "")
 
 	cfProxyInfo.exitLoc = info.CountryCode
-	cfProxyInfo.cfLoc = cfRelayLoc
-	return cfProxyInfo
+	cfProxyInfo.cfLoc = trace.Loc
+	return cfProxyInfo, trace
 }
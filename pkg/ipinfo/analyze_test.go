@@ -38,3 +38,48 @@ func TestGetAnalyzed(t *testing.T) {
 		}
 	}
 }
+
+func TestAnalyzeIPData(t *testing.T) {
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithDBReader(db))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	tests := []struct {
+		ip, country   string
+		cfLoc, cfIP   string
+		wantCategory  Category
+		wantTag       string
+		wantCFProxied bool
+	}{
+		{"1.2.4.8", "CN", "", "", CategoryLocalISP, "Local ISP", false},
+		{"8.8.8.8", "US", "US", "8.8.8.8", CategoryNoCF, "US²", false},
+		{"104.16.132.229", "US", "US", "198.51.100.1", CategoryCFSameCountry, "US¹⁺", true},
+		{"104.16.132.229", "HK", "US", "198.51.100.1", CategoryCFDifferentCountry, "HK¹-US⁰", true},
+		{"104.16.132.229", "US", "US", "104.16.132.229", CategoryNoCF, "US²", false},
+		{"104.28.163.56", "US", "US", "104.28.163.56", CategoryWARP, "US³", false},
+	}
+	for _, tt := range tests {
+		info := IPData{IPv4: tt.ip, CountryCode: tt.country, ContinentCode: "AS"}
+		cli.CheckCDN(&info)
+		r := cli.analyzeIPData(context.Background(), info, tt.cfLoc, tt.cfIP)
+		if r.Category != tt.wantCategory || r.Tag != tt.wantTag || r.IsCFProxy != tt.wantCFProxied {
+			t.Errorf("%s/%s: 分析结果 %s %q %v, 期望 %s %q %v", tt.ip, tt.country,
+				r.Category, r.Tag, r.IsCFProxy, tt.wantCategory, tt.wantTag, tt.wantCFProxied)
+		}
+		if r.IP != tt.ip || len(r.Reasons) == 0 {
+			t.Errorf("%s: 结果不完整: %+v", tt.ip, r)
+		}
+	}
+
+	if b, _ := CategoryCFDifferentCountry.MarshalText(); string(b) != "cf_different_country" {
+		t.Errorf("类别序列化错误: %s", b)
+	}
+}
@@ -4,19 +4,19 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
-
-	"github.com/sinspired/checkip/internal/config"
 )
 
 // CheckCloudflareQuick 快速检查是否可访问 CF
 func (c *Client) CheckCloudflareQuick() bool {
-	ok, err := c.checkCFEndpoint("http://cp.cloudflare.com/generate_204", 204)
+	return c.CheckCloudflareQuickContext(context.Background())
+}
+
+// CheckCloudflareQuickContext 快速检查是否可访问 CF, ctx 取消时立即返回
+func (c *Client) CheckCloudflareQuickContext(ctx context.Context) bool {
+	ok, err := c.checkCFEndpoint(ctx, "http://cp.cloudflare.com/generate_204", 204)
 	if err == nil && ok {
 		slog.Debug("Cloudflare 可达，但未获取到 loc/ip")
 		return true
@@ -26,14 +26,19 @@ func (c *Client) CheckCloudflareQuick() bool {
 
 // CheckCloudflare 检测当前客户端是否可以访问 Cloudflare CDN
 func (c *Client) CheckCloudflare() (bool, string, string) {
-	cfRelayLoc, cfRelayIP := c.GetCFTrace()
+	return c.CheckCloudflareContext(context.Background())
+}
+
+// CheckCloudflareContext 检测当前客户端是否可以访问 Cloudflare CDN, ctx 取消时立即返回
+func (c *Client) CheckCloudflareContext(ctx context.Context) (bool, string, string) {
+	cfRelayLoc, cfRelayIP := c.GetCFTraceContext(ctx)
 
 	if cfRelayLoc != "" && cfRelayIP != "" {
 		slog.Debug(fmt.Sprintf("Cloudflare CDN 检测成功: loc=%s, ip=%s", cfRelayLoc, cfRelayIP))
 		return true, cfRelayLoc, cfRelayIP
 	}
 
-	ok, err := c.checkCFEndpoint("https://cloudflare.com", 200)
+	ok, err := c.checkCFEndpoint(ctx, "https://cloudflare.com", 200)
 	if err == nil && ok {
 		slog.Debug("Cloudflare 可达，但未获取到 loc/ip")
 		return true, "", ""
@@ -44,103 +49,30 @@ func (c *Client) CheckCloudflare() (bool, string, string) {
 
 // GetCFTrace 获取 Cloudflare Trace 的 loc 和 ip,并设置 10s 超时
 func (c *Client) GetCFTrace() (string, string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	return c.FetchCFTraceFirstConcurrent(ctx, cancel)
+	return c.GetCFTraceContext(context.Background())
+}
+
+// GetCFTraceContext 获取 Cloudflare Trace 的 loc 和 ip, 在 ctx 基础上设置 10s 超时
+func (c *Client) GetCFTraceContext(ctx context.Context) (string, string) {
+	t, _ := c.GetCFTraceResult(ctx)
+	return t.Loc, t.IP
 }
 
 // FetchCFTraceFirstConcurrent 并发处理 FetchCFCDNTrace
 func (c *Client) FetchCFTraceFirstConcurrent(ctx context.Context, cancel context.CancelFunc) (string, string) {
-	type result struct {
-		loc string
-		ip  string
-	}
-
-	// 乱序 + 截取前3, 减轻网络负载
-	apis := shuffle(config.CF_CDN_APIS)
-	if len(apis) > 3 {
-		apis = apis[:3]
-	}
-
-	resultChan := make(chan result, 1)
-	var once sync.Once
-	var wg sync.WaitGroup
-
-	retries := 2
-
-	for _, baseURL := range apis {
-		wg.Add(1)
-		go func(url string) {
-			defer wg.Done()
-			for range retries {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-				loc, ip := c.FetchCFTrace(ctx, url)
-				if loc != "" && ip != "" {
-					once.Do(func() {
-						resultChan <- result{loc, ip}
-						cancel()
-					})
-					return
-				}
-			}
-		}(baseURL)
-	}
-
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	select {
-	case r := <-resultChan:
-		return r.loc, r.ip
-	case <-ctx.Done():
-		return "", ""
-	}
+	t, _ := c.fetchCFTraceFirst(ctx, cancel)
+	return t.Loc, t.IP
 }
 
 // FetchCFTrace 从cloudflare 的cdn-cgi/trace API获取CDN节点位置
 func (c *Client) FetchCFTrace(ctx context.Context, baseURL string) (string, string) {
-	url := fmt.Sprintf("%s/cdn-cgi/trace", baseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", ""
-	}
-
-	for key, value := range cfCommonHeaders() {
-		req.Header.Set(key, value)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return "", ""
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024))
-	if err != nil {
-		return "", ""
-	}
-
-	var loc, ip string
-	for _, line := range strings.Split(string(body), "\n") {
-		if strings.HasPrefix(line, "loc=") {
-			loc = strings.TrimPrefix(line, "loc=")
-		}
-		if strings.HasPrefix(line, "ip=") {
-			ip = strings.TrimPrefix(line, "ip=")
-		}
-	}
-	return loc, ip
+	t, _ := c.FetchCFTraceResult(ctx, baseURL)
+	return t.Loc, t.IP
 }
 
 // checkCFEndpoint 检查指定的 Cloudflare 端点是否可达，并返回是否成功和错误信息
-func (c *Client) checkCFEndpoint(url string, expectedStatus int) (bool, error) {
-	req, err := http.NewRequest("HEAD", url, nil)
+func (c *Client) checkCFEndpoint(ctx context.Context, url string, expectedStatus int) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,322 @@
+package ipinfo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+// CDNCloudflare 内置 Cloudflare 网段的名称, 仅该 CDN 会进一步通过 /cdn-cgi/trace 分析
+const CDNCloudflare = data.CDNCloudflare
+
+// CDNProvider 命名的 CDN 网段及 AS 编号集合
+type CDNProvider struct {
+	Name     string
+	Prefixes []netip.Prefix
+	ASNs     []uint // 需加载 ASN 数据库才能按 AS 编号识别
+}
+
+// CDNMatch CDN 识别结果
+type CDNMatch struct {
+	Name   string       // CDN 名称
+	Prefix netip.Prefix // 命中的网段; 按 AS 编号命中时为 ASN 数据库中的网段
+	ASN    uint         // 按 AS 编号命中时的编号
+}
+
+// CDNRegistry 并发安全的 CDN 网段注册表, 网段重叠时取最长前缀, 同一网段属于多个 CDN 时取先注册的
+type CDNRegistry struct {
+	mu        sync.RWMutex
+	providers []CDNProvider
+	table     *PrefixTable[string] // 网段 -> CDN 名称, 注册时重建
+	gen       uint64               // 注册次数, 用于判断是否需要重新加载
+}
+
+// NewCDNRegistry 创建 CDN 注册表
+func NewCDNRegistry(providers ...CDNProvider) *CDNRegistry {
+	r := &CDNRegistry{}
+	for _, p := range providers {
+		r.register(p)
+	}
+	r.rebuild()
+	return r
+}
+
+// defaultCDNRegistry 由内置网段构建, 仅解析一次并在未指定注册表的客户端间共享
+var defaultCDNRegistry = sync.OnceValue(func() *CDNRegistry {
+	embedded := data.CDNRanges()
+	names := make([]string, 0, len(embedded))
+	for name := range embedded {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var providers []CDNProvider
+	for _, name := range names {
+		p, err := ParseCDNRanges(name, strings.NewReader(embedded[name]))
+		if err != nil {
+			slog.Debug(fmt.Sprintf("解析内置 %s 网段失败: %v", name, err))
+			continue
+		}
+		providers = append(providers, p)
+	}
+	r := NewCDNRegistry(providers...)
+
+	// Cloudflare 网段运行时更新后同步到内置注册表
+	data.OnCfCdnRangesUpdate(func() {
+		p, err := ParseCDNRanges(CDNCloudflare, strings.NewReader(data.CfCdnRangesText()))
+		if err != nil {
+			slog.Debug(fmt.Sprintf("解析 Cloudflare 网段失败: %v", err))
+			return
+		}
+		r.Register(p)
+	})
+	return r
+})
+
+// DefaultCDNRegistry 返回内置 CDN 注册表的副本, 可在其上注册或覆盖 CDN;
+// 副本不会随 MergeCloudflareRanges 更新
+func DefaultCDNRegistry() *CDNRegistry {
+	return defaultCDNRegistry().Clone()
+}
+
+// MergeCloudflareRanges 下载 Cloudflare 网段列表并合并到内置注册表, 只增不减; 地址为空时使用官方地址,
+// 校验通过后缓存到数据目录, 下次启动时自动加载; 失败时保留当前网段
+func MergeCloudflareRanges(ctx context.Context, hc *http.Client, v4URL, v6URL string) error {
+	defaultCDNRegistry() // 确保内置注册表已订阅更新
+	return data.MergeCfCdnIPRanges(ctx, hc, v4URL, v6URL)
+}
+
+// Register 注册 CDN, 同名时替换
+func (r *CDNRegistry) Register(p CDNProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.register(p)
+	r.rebuild()
+	r.gen++
+}
+
+// generation 返回注册次数
+func (r *CDNRegistry) generation() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.gen
+}
+
+// provider 返回指定名称的 CDN
+func (r *CDNRegistry) provider(name string) (CDNProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i := slices.IndexFunc(r.providers, func(x CDNProvider) bool { return x.Name == name })
+	if i < 0 {
+		return CDNProvider{}, false
+	}
+	return r.providers[i], true
+}
+
+// register 写入提供者列表, 调用方需持有锁
+func (r *CDNRegistry) register(p CDNProvider) {
+	if i := slices.IndexFunc(r.providers, func(x CDNProvider) bool { return x.Name == p.Name }); i >= 0 {
+		r.providers[i] = p
+		return
+	}
+	r.providers = append(r.providers, p)
+}
+
+// rebuild 重建前缀树, 逆序写入使先注册的 CDN 在同一网段上优先; 调用方需持有锁
+func (r *CDNRegistry) rebuild() {
+	table := &PrefixTable[string]{}
+	for _, p := range slices.Backward(r.providers) {
+		for _, prefix := range p.Prefixes {
+			table.Insert(prefix, p.Name)
+		}
+	}
+	r.table = table
+}
+
+// LoadFile 从文件加载网段并注册为 name, 同名时替换
+func (r *CDNRegistry) LoadFile(name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p, err := ParseCDNRanges(name, f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	r.Register(p)
+	return nil
+}
+
+// Names 返回已注册的 CDN 名称
+func (r *CDNRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, len(r.providers))
+	for i, p := range r.providers {
+		out[i] = p.Name
+	}
+	return out
+}
+
+// Clone 复制注册表, 副本的注册操作不影响原表
+func (r *CDNRegistry) Clone() *CDNRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	// 前缀树构建后只读, 可与副本共享
+	return &CDNRegistry{providers: slices.Clone(r.providers), table: r.table}
+}
+
+// Match 按网段识别 CDN
+func (r *CDNRegistry) Match(addr netip.Addr) (CDNMatch, bool) {
+	r.mu.RLock()
+	table := r.table
+	r.mu.RUnlock()
+
+	prefix, name, ok := table.Lookup(addr)
+	if !ok {
+		return CDNMatch{}, false
+	}
+	return CDNMatch{Name: name, Prefix: prefix}, true
+}
+
+// MatchASN 按 AS 编号识别 CDN
+func (r *CDNRegistry) MatchASN(asn uint) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.providers {
+		if slices.Contains(p.ASNs, asn) {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
+
+// hasASNs 是否有 CDN 需要按 AS 编号识别
+func (r *CDNRegistry) hasASNs() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.ContainsFunc(r.providers, func(p CDNProvider) bool { return len(p.ASNs) > 0 })
+}
+
+// ParseCDNRanges 解析网段列表: 每行一个 CIDR、IP 或 AS 编号(如 AS54113), # 开头为注释
+func ParseCDNRanges(name string, rd io.Reader) (CDNProvider, error) {
+	p := CDNProvider{Name: name}
+	scanner := bufio.NewScanner(rd)
+	lineCount := 0
+	for scanner.Scan() {
+		lineCount++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if num, ok := strings.CutPrefix(strings.ToUpper(line), "AS"); ok {
+			asn, err := strconv.ParseUint(num, 10, 32)
+			if err != nil {
+				slog.Debug(fmt.Sprintf("%s 第 %d 行 AS 编号无效: %s", name, lineCount, line))
+				continue
+			}
+			p.ASNs = append(p.ASNs, uint(asn))
+			continue
+		}
+
+		if !strings.Contains(line, "/") {
+			addr, err := netip.ParseAddr(line)
+			if err != nil {
+				slog.Debug(fmt.Sprintf("%s 第 %d 行 IP 无效: %s", name, lineCount, line))
+				continue
+			}
+			p.Prefixes = append(p.Prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(line)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("%s 第 %d 行 CIDR 无效: %s", name, lineCount, line))
+			continue
+		}
+		p.Prefixes = append(p.Prefixes, prefix.Masked())
+	}
+	if err := scanner.Err(); err != nil {
+		return p, err
+	}
+	if len(p.Prefixes) == 0 && len(p.ASNs) == 0 {
+		return p, fmt.Errorf("未解析到任何网段或 AS 编号")
+	}
+	return p, nil
+}
+
+// cdnRegistry 返回客户端使用的注册表
+func (c *Client) cdnRegistry() *CDNRegistry {
+	if r := c.cdn.Load(); r != nil {
+		return r
+	}
+	return c.cdnBaseRegistry()
+}
+
+// CDNProvider 返回客户端当前生效的指定 CDN, 包含传入的网段、追加的网段文件及重新加载的结果
+func (c *Client) CDNProvider(name string) (CDNProvider, bool) {
+	return c.cdnRegistry().provider(name)
+}
+
+// cdnBaseRegistry 返回指定的注册表, 未指定时为内置注册表
+func (c *Client) cdnBaseRegistry() *CDNRegistry {
+	if c.cdnBase != nil {
+		return c.cdnBase
+	}
+	return defaultCDNRegistry()
+}
+
+// matchCDN 先按网段识别, 未命中时按 ASN 数据库中的 AS 编号识别
+func (c *Client) matchCDN(addr netip.Addr) (CDNMatch, bool) {
+	reg := c.cdnRegistry()
+	if m, ok := reg.Match(addr); ok {
+		return m, true
+	}
+	if !reg.hasASNs() {
+		return CDNMatch{}, false
+	}
+	asn, prefix := c.lookupASNNumber(addr)
+	if asn == 0 {
+		return CDNMatch{}, false
+	}
+	if name, ok := reg.MatchASN(asn); ok {
+		return CDNMatch{Name: name, Prefix: prefix, ASN: asn}, true
+	}
+	return CDNMatch{}, false
+}
+
+// lookupASNNumber 从 ASN 及自定义数据库中查询 AS 编号
+func (c *Client) lookupASNNumber(addr netip.Addr) (uint, netip.Prefix) {
+	for _, src := range c.dbs {
+		if src.kind != DBASN && src.kind != DBCustom {
+			continue
+		}
+		var rec struct {
+			ASN uint `maxminddb:"autonomous_system_number"`
+		}
+		reader, release := src.acquire()
+		if reader == nil {
+			continue
+		}
+		res := reader.Lookup(addr)
+		err := res.Decode(&rec)
+		release()
+		if err == nil && rec.ASN != 0 {
+			return rec.ASN, res.Prefix()
+		}
+	}
+	return 0, netip.Prefix{}
+}
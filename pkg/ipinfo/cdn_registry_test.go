@@ -0,0 +1,107 @@
+package ipinfo
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestParseCDNRanges(t *testing.T) {
+	p, err := ParseCDNRanges("test", strings.NewReader("# comment\nAS54113\n151.101.0.0/16\n1.2.3.4\n2a04:4e42::/32\ninvalid\n"))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !slices.Equal(p.ASNs, []uint{54113}) {
+		t.Errorf("AS 编号解析错误: %v", p.ASNs)
+	}
+	want := []string{"151.101.0.0/16", "1.2.3.4/32", "2a04:4e42::/32"}
+	if len(p.Prefixes) != len(want) {
+		t.Fatalf("网段数量错误: %v", p.Prefixes)
+	}
+	for i, w := range want {
+		if p.Prefixes[i].String() != w {
+			t.Errorf("网段解析错误: got %s, want %s", p.Prefixes[i], w)
+		}
+	}
+
+	if _, err := ParseCDNRanges("empty", strings.NewReader("# nothing\n")); err == nil {
+		t.Error("空列表应返回错误")
+	}
+}
+
+func TestCDNRegistryMatch(t *testing.T) {
+	reg := DefaultCDNRegistry()
+	for _, name := range []string{CDNCloudflare, "fastly", "cloudfront", "akamai", "gcore", "bunny"} {
+		if !slices.Contains(reg.Names(), name) {
+			t.Errorf("内置注册表缺少 %s", name)
+		}
+	}
+
+	tests := []struct {
+		ip   string
+		name string
+	}{
+		{"104.28.163.56", CDNCloudflare},
+		{"151.101.1.69", "fastly"},
+		{"2a04:4e42::1", "fastly"},
+		{"13.32.1.1", "cloudfront"},
+		{"45.65.122.98", ""},
+	}
+	for _, tt := range tests {
+		m, ok := reg.Match(netip.MustParseAddr(tt.ip))
+		if m.Name != tt.name || ok != (tt.name != "") {
+			t.Errorf("%s 识别错误: got %q, want %q", tt.ip, m.Name, tt.name)
+		}
+	}
+
+	// 重叠时取最长前缀
+	reg.Register(CDNProvider{Name: "overlay", Prefixes: []netip.Prefix{netip.MustParsePrefix("151.101.1.0/24")}})
+	if m, _ := reg.Match(netip.MustParseAddr("151.101.1.69")); m.Name != "overlay" || m.Prefix.Bits() != 24 {
+		t.Errorf("最长前缀匹配错误: %+v", m)
+	}
+	if slices.Contains(defaultCDNRegistry().Names(), "overlay") {
+		t.Error("副本的注册操作不应影响内置注册表")
+	}
+
+	if name, ok := reg.MatchASN(199524); !ok || name != "gcore" {
+		t.Errorf("AS 编号识别错误: %q", name)
+	}
+}
+
+func TestWithCDNRangeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mycdn.txt")
+	if err := os.WriteFile(path, []byte("45.65.122.0/24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithDBReader(db), WithCDNRangeFile("mycdn", path))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	info := &IPData{IPv4: "45.65.122.98"}
+	if !cli.CheckCDN(info) || info.CDN != "mycdn" || info.CDNPrefix != "45.65.122.0/24" {
+		t.Errorf("自定义 CDN 识别错误: %+v", info)
+	}
+
+	info = &IPData{IPv4: "104.28.163.56"}
+	if !cli.CheckCDN(info) || info.CDN != CDNCloudflare {
+		t.Errorf("内置 CDN 识别错误: %+v", info)
+	}
+
+	if _, err := New(WithDBReader(db), WithCDNRangeFile("missing", filepath.Join(t.TempDir(), "none.txt"))); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
@@ -0,0 +1,137 @@
+package ipinfo
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+)
+
+// cdnFile 用户提供的 CDN 网段文件
+type cdnFile struct {
+	name  string
+	path  string
+	extra bool // 追加到同名 CDN 而非替换
+}
+
+// fileStamp 用于判断文件是否变化
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// CDNProviderFromIPNets 将 map[ipv4|ipv6][]*net.IPNet 形式的网段转换为 CDNProvider
+func CDNProviderFromIPNets(name string, ranges map[string][]*net.IPNet) CDNProvider {
+	p := CDNProvider{Name: name}
+	for _, version := range []string{"ipv4", "ipv6"} {
+		for _, n := range ranges[version] {
+			if n == nil {
+				continue
+			}
+			addr, ok := netip.AddrFromSlice(n.IP)
+			if !ok {
+				continue
+			}
+			if version == "ipv4" {
+				addr = addr.Unmap()
+			}
+			ones, _ := n.Mask.Size()
+			if addr.Is4() && ones > 32 {
+				continue
+			}
+			p.Prefixes = append(p.Prefixes, netip.PrefixFrom(addr, ones).Masked())
+		}
+	}
+	return p
+}
+
+// hasCDNSources 是否指定了额外 CDN 或网段文件
+func (c *Client) hasCDNSources() bool {
+	return len(c.cdnProviders) > 0 || len(c.cdnFiles) > 0
+}
+
+// ReloadCDNRanges 重新读取 CDN 网段文件并原子替换当前注册表, 失败时保留原注册表;
+// 未指定额外 CDN 或网段文件时无需重新加载
+func (c *Client) ReloadCDNRanges() error {
+	if !c.hasCDNSources() {
+		return nil
+	}
+
+	base := c.cdnBaseRegistry()
+	gen := base.generation()
+	reg := base.Clone()
+
+	reg.mu.Lock()
+	for _, p := range c.cdnProviders {
+		reg.register(p)
+	}
+	for _, f := range c.cdnFiles {
+		fp, err := os.Open(f.path)
+		if err != nil {
+			reg.mu.Unlock()
+			return fmt.Errorf("load cdn ranges: %w", err)
+		}
+		p, err := ParseCDNRanges(f.name, fp)
+		fp.Close()
+		if err != nil {
+			reg.mu.Unlock()
+			return fmt.Errorf("load cdn ranges: %s: %w", f.path, err)
+		}
+		if f.extra {
+			if i := slices.IndexFunc(reg.providers, func(x CDNProvider) bool { return x.Name == f.name }); i >= 0 {
+				cur := reg.providers[i]
+				p.Prefixes = append(slices.Clone(cur.Prefixes), p.Prefixes...)
+				p.ASNs = append(slices.Clone(cur.ASNs), p.ASNs...)
+			}
+		}
+		reg.register(p)
+	}
+	reg.rebuild()
+	reg.mu.Unlock()
+
+	c.cdn.Store(reg)
+	c.cdnBaseGen.Store(gen)
+	slog.Debug(fmt.Sprintf("已加载 CDN 网段: %v", reg.Names()))
+	return nil
+}
+
+// watchCDNRanges 定期检查网段文件及基础注册表, 变化时重新加载; stamps 为加载时的文件记录
+func (c *Client) watchCDNRanges(interval time.Duration, stamps map[string]fileStamp, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := c.cdnFileStamps()
+		if maps.Equal(now, stamps) && c.cdnBaseRegistry().generation() == c.cdnBaseGen.Load() {
+			continue
+		}
+		// 无论成功与否都更新记录, 避免文件损坏时反复报错
+		stamps = now
+		if err := c.ReloadCDNRanges(); err != nil {
+			slog.Warn("CDN 网段重新加载失败, 继续使用原网段", "error", err)
+		}
+	}
+}
+
+// cdnFileStamps 记录网段文件的修改时间及大小, 文件不存在时为零值
+func (c *Client) cdnFileStamps() map[string]fileStamp {
+	out := make(map[string]fileStamp, len(c.cdnFiles))
+	for _, f := range c.cdnFiles {
+		if fi, err := os.Stat(f.path); err == nil {
+			out[f.path] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+		} else {
+			out[f.path] = fileStamp{}
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package ipinfo
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestReloadCDNRanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cloudflare_extra.txt")
+	if err := os.WriteFile(path, []byte("45.65.122.0/24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithDBReader(db), WithExtraCDNRangeFile(CDNCloudflare, path), WithCDNReload(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	check := func(ip, want string) bool {
+		info := &IPData{IPv4: ip}
+		cli.CheckCDN(info)
+		return info.CDN == want
+	}
+
+	// 追加的网段与内置网段同时生效
+	if !check("45.65.122.98", CDNCloudflare) || !check("104.28.163.56", CDNCloudflare) {
+		t.Fatal("追加网段未生效")
+	}
+
+	if p, ok := cli.CDNProvider(CDNCloudflare); !ok || !slices.Contains(p.Prefixes, netip.MustParsePrefix("45.65.122.0/24")) {
+		t.Error("生效的 Cloudflare 网段未包含追加的网段")
+	}
+
+	// 文件变化后自动重新加载
+	if err := os.WriteFile(path, []byte("45.65.122.0/24\n198.51.100.0/24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !check("198.51.100.7", CDNCloudflare) {
+		if time.Now().After(deadline) {
+			t.Fatal("文件变化后未重新加载")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 文件无效时保留原网段
+	if err := os.WriteFile(path, []byte("# empty\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.ReloadCDNRanges(); err == nil {
+		t.Error("无效文件应返回错误")
+	}
+	if !check("198.51.100.7", CDNCloudflare) {
+		t.Error("重新加载失败时不应替换原网段")
+	}
+}
+
+func TestCDNProviderFromIPNets(t *testing.T) {
+	_, v4, _ := net.ParseCIDR("45.65.122.0/24")
+	_, v6, _ := net.ParseCIDR("2001:db8::/32")
+	p := CDNProviderFromIPNets(CDNCloudflare, map[string][]*net.IPNet{"ipv4": {v4}, "ipv6": {v6}})
+	if len(p.Prefixes) != 2 || p.Prefixes[0].String() != "45.65.122.0/24" || p.Prefixes[1].String() != "2001:db8::/32" {
+		t.Errorf("网段转换错误: %v", p.Prefixes)
+	}
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	// 传入的网段替换内置 Cloudflare 网段
+	cli, err := New(WithDBReader(db), WithCDNProvider(p))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+	if info := (&IPData{IPv4: "45.65.122.98"}); !cli.CheckCDN(info) || info.CDN != CDNCloudflare {
+		t.Errorf("传入网段未生效: %+v", info)
+	}
+	if info := (&IPData{IPv4: "104.28.163.56"}); cli.CheckCDN(info) {
+		t.Errorf("内置 Cloudflare 网段应被替换: %+v", info)
+	}
+}
@@ -0,0 +1,210 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sinspired/checkip/internal/config"
+)
+
+// CFTrace Cloudflare /cdn-cgi/trace 的解析结果
+type CFTrace struct {
+	FL          string    // 前端服务标识
+	Host        string    // 请求的主机名
+	IP          string    // Cloudflare 看到的客户端 IP
+	Timestamp   time.Time // 服务端时间
+	VisitScheme string    // http / https
+	UserAgent   string
+	Colo        string // 服务的数据中心 IATA 代码, 如 LAX
+	Sliver      string
+	HTTP        string // http/1.1 / http/2 / http/3
+	Loc         string // 客户端 IP 所在国家代码
+	TLS         string // TLSv1.2 / TLSv1.3, 未使用 TLS 时为 off
+	SNI         string // plaintext / encrypted / off
+	WARP        string // off / on / plus
+	Gateway     string // Zero Trust 网关: off / on
+	RBI         string // 远程浏览器隔离: off / on
+	KEX         string // TLS 密钥交换算法, 如 X25519MLKEM768
+
+	Fields map[string]string // 全部原始字段
+}
+
+// TLS13 是否协商了 TLS 1.3
+func (t CFTrace) TLS13() bool {
+	return t.TLS == "TLSv1.3"
+}
+
+// HTTP3 是否通过 HTTP/3 访问
+func (t CFTrace) HTTP3() bool {
+	return t.HTTP == "http/3"
+}
+
+// WARPEnabled 路径上是否启用了 WARP(含 WARP+)
+func (t CFTrace) WARPEnabled() bool {
+	return t.WARP == "on" || t.WARP == "plus"
+}
+
+// ParseCFTrace 解析 /cdn-cgi/trace 的 key=value 响应, 缺少 ip 或 loc 时返回错误
+func ParseCFTrace(body []byte) (CFTrace, error) {
+	t := CFTrace{Fields: make(map[string]string)}
+	for _, line := range strings.Split(string(body), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || key == "" {
+			continue
+		}
+		t.Fields[key] = value
+
+		switch key {
+		case "fl":
+			t.FL = value
+		case "h":
+			t.Host = value
+		case "ip":
+			t.IP = value
+		case "ts":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				sec, frac := math.Modf(f)
+				t.Timestamp = time.Unix(int64(sec), int64(frac*1e9))
+			}
+		case "visit_scheme":
+			t.VisitScheme = value
+		case "uag":
+			t.UserAgent = value
+		case "colo":
+			t.Colo = value
+		case "sliver":
+			t.Sliver = value
+		case "http":
+			t.HTTP = value
+		case "loc":
+			t.Loc = value
+		case "tls":
+			t.TLS = value
+		case "sni":
+			t.SNI = value
+		case "warp":
+			t.WARP = value
+		case "gateway":
+			t.Gateway = value
+		case "rbi":
+			t.RBI = value
+		case "kex":
+			t.KEX = value
+		}
+	}
+
+	if t.IP == "" || t.Loc == "" {
+		return t, fmt.Errorf("trace 缺少 ip 或 loc 字段: %q", body)
+	}
+	return t, nil
+}
+
+// FetchCFTraceResult 从指定地址的 /cdn-cgi/trace 获取完整 trace 信息
+func (c *Client) FetchCFTraceResult(ctx context.Context, baseURL string) (CFTrace, error) {
+	url := fmt.Sprintf("%s/cdn-cgi/trace", baseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return CFTrace{}, err
+	}
+
+	for key, value := range cfCommonHeaders() {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return CFTrace{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return CFTrace{}, fmt.Errorf("HTTP 状态码 %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024))
+	if err != nil {
+		return CFTrace{}, err
+	}
+	return ParseCFTrace(body)
+}
+
+// GetCFTraceResult 并发请求多个 Cloudflare trace 地址, 返回最先成功的完整 trace 信息, 在 ctx 基础上设置 10s 超时
+func (c *Client) GetCFTraceResult(ctx context.Context) (CFTrace, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return c.fetchCFTraceFirst(ctx, cancel)
+}
+
+// fetchCFTraceFirst 并发请求按健康得分排序的前 3 个 trace 地址, 返回最先成功的结果
+func (c *Client) fetchCFTraceFirst(ctx context.Context, cancel context.CancelFunc) (CFTrace, error) {
+	// 按健康得分排序 + 截取前3, 减轻网络负载
+	apis := rankByHealth(c.health, config.CF_CDN_APIS, func(s string) string { return s })
+	if len(apis) > 3 {
+		apis = apis[:3]
+	}
+
+	resultChan := make(chan CFTrace, 1)
+	var once sync.Once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+
+	retries := 2
+
+	for _, baseURL := range apis {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			for range retries {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				start := time.Now()
+				trace, err := c.FetchCFTraceResult(ctx, url)
+				if err == nil || ctx.Err() == nil {
+					c.health.record(url, time.Since(start), err == nil)
+				}
+				if err == nil {
+					once.Do(func() {
+						resultChan <- trace
+						cancel()
+					})
+					return
+				}
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", url, err))
+				mu.Unlock()
+			}
+		}(baseURL)
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	select {
+	case t, ok := <-resultChan:
+		if ok {
+			return t, nil
+		}
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) == 0 {
+		return CFTrace{}, ctx.Err()
+	}
+	return CFTrace{}, errors.Join(errs...)
+}
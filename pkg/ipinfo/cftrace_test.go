@@ -0,0 +1,83 @@
+package ipinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const sampleCFTrace = `fl=29f98
+h=www.cloudflare.com
+ip=104.28.163.56
+ts=1700000000.5
+visit_scheme=https
+uag=Mozilla/5.0
+colo=LAX
+sliver=none
+http=http/3
+loc=US
+tls=TLSv1.3
+sni=encrypted
+warp=plus
+gateway=off
+rbi=off
+kex=X25519MLKEM768
+`
+
+func TestParseCFTrace(t *testing.T) {
+	tr, err := ParseCFTrace([]byte(sampleCFTrace))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if tr.IP != "104.28.163.56" || tr.Loc != "US" || tr.Colo != "LAX" || tr.Host != "www.cloudflare.com" {
+		t.Errorf("基础字段解析错误: %+v", tr)
+	}
+	if !tr.HTTP3() || !tr.TLS13() || !tr.WARPEnabled() || tr.SNI != "encrypted" || tr.Gateway != "off" {
+		t.Errorf("连接字段解析错误: %+v", tr)
+	}
+	if tr.KEX != "X25519MLKEM768" || tr.Fields["sliver"] != "none" {
+		t.Errorf("原始字段解析错误: %+v", tr)
+	}
+	if tr.Timestamp.Unix() != 1700000000 || tr.Timestamp.Nanosecond() != 500000000 {
+		t.Errorf("时间戳解析错误: %v", tr.Timestamp)
+	}
+
+	if _, err := ParseCFTrace([]byte("<html>captive portal</html>")); err == nil {
+		t.Error("缺少 ip/loc 时应返回错误")
+	}
+}
+
+func TestFetchCFTraceResult(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cdn-cgi/trace" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(sampleCFTrace))
+	}))
+	defer srv.Close()
+
+	cli, err := New()
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	tr, err := cli.FetchCFTraceResult(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("获取 trace 失败: %v", err)
+	}
+	if tr.Colo != "LAX" || tr.WARP != "plus" {
+		t.Errorf("trace 字段错误: %+v", tr)
+	}
+
+	// 旧接口保持 (loc, ip) 返回值
+	if loc, ip := cli.FetchCFTrace(context.Background(), srv.URL); loc != "US" || ip != "104.28.163.56" {
+		t.Errorf("FetchCFTrace 返回错误: %s %s", loc, ip)
+	}
+
+	if _, err := cli.FetchCFTraceResult(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("非 200 状态码应返回错误")
+	}
+}
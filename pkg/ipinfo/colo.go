@@ -0,0 +1,66 @@
+package ipinfo
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+// Colo Cloudflare 数据中心信息
+type Colo struct {
+	Code        string // IATA 代码, 如 HKG
+	City        string
+	CountryCode string // 国家代码（ISO）
+	Latitude    float64
+	Longitude   float64
+}
+
+// coloTable 内置数据中心表, 键为大写 IATA 代码
+var coloTable = sync.OnceValue(func() map[string]Colo {
+	out := make(map[string]Colo)
+	scanner := bufio.NewScanner(strings.NewReader(data.ColoTable()))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(fields[3], 64)
+		lon, err2 := strconv.ParseFloat(fields[4], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		out[fields[0]] = Colo{
+			Code:        fields[0],
+			City:        fields[1],
+			CountryCode: fields[2],
+			Latitude:    lat,
+			Longitude:   lon,
+		}
+	}
+	return out
+})
+
+// LookupColo 根据 IATA 代码查询 Cloudflare 数据中心, 不区分大小写
+func LookupColo(code string) (Colo, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	colo, ok := coloTable()[code]
+	return colo, ok
+}
+
+// coloFromTrace 根据 trace 的 colo= 字段获取数据中心, 未收录时仅保留代码
+func coloFromTrace(trace CFTrace) Colo {
+	if trace.Colo == "" {
+		return Colo{}
+	}
+	if colo, ok := LookupColo(trace.Colo); ok {
+		return colo
+	}
+	return Colo{Code: strings.ToUpper(trace.Colo)}
+}
@@ -0,0 +1,38 @@
+package ipinfo
+
+import "testing"
+
+func TestLookupColo(t *testing.T) {
+	colo, ok := LookupColo("hkg")
+	if !ok || colo.Code != "HKG" || colo.CountryCode != "HK" || colo.City != "Hong Kong" {
+		t.Errorf("查询 HKG 错误: %+v", colo)
+	}
+	if colo.Latitude < 22 || colo.Latitude > 23 || colo.Longitude < 113 || colo.Longitude > 115 {
+		t.Errorf("HKG 坐标错误: %+v", colo)
+	}
+	if _, ok := LookupColo("XXX"); ok {
+		t.Error("未收录的代码不应命中")
+	}
+
+	// 未收录时保留代码
+	if colo := coloFromTrace(CFTrace{Colo: "zzz"}); colo.Code != "ZZZ" || colo.CountryCode != "" {
+		t.Errorf("未收录代码处理错误: %+v", colo)
+	}
+	if colo := coloFromTrace(CFTrace{Colo: "SIN"}); colo.CountryCode != "SG" {
+		t.Errorf("trace 数据中心解析错误: %+v", colo)
+	}
+}
+
+func TestColoTag(t *testing.T) {
+	r := &AnalyzeResult{Loc: "US", Trace: CFTrace{Loc: "US"}, Category: CategoryCFSameCountry, Colo: Colo{Code: "HKG"}}
+	if tag := (&Client{}).formatTag(r); tag != "US¹⁺" {
+		t.Errorf("未启用时不应附加数据中心: %q", tag)
+	}
+	if tag := (&Client{coloTag: true}).formatTag(r); tag != "US¹⁺@HKG" {
+		t.Errorf("数据中心后缀错误: %q", tag)
+	}
+	r.Colo = Colo{}
+	if tag := (&Client{coloTag: true}).formatTag(r); tag != "US¹⁺" {
+		t.Errorf("未知数据中心时不应附加后缀: %q", tag)
+	}
+}
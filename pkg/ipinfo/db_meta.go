@@ -0,0 +1,79 @@
+package ipinfo
+
+import (
+	"time"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+// DBOrigin 数据库来源
+type DBOrigin string
+
+const (
+	DBOriginEmbedded   DBOrigin = "embedded"   // 数据目录中的默认数据库, 无更新记录, 即由内置数据解压
+	DBOriginDownloaded DBOrigin = "downloaded" // 由更新任务下载, 更新记录与当前数据库的构建时间一致
+	DBOriginFile       DBOrigin = "file"       // 指定路径的数据库文件
+	DBOriginReader     DBOrigin = "reader"     // 调用方传入的阅读器, 路径未知
+)
+
+// DBMeta 已加载数据库的元数据
+type DBMeta struct {
+	Kind         DBType    `json:"kind"`
+	DatabaseType string    `json:"database_type"`
+	BuildEpoch   uint      `json:"build_epoch"`
+	BuildTime    time.Time `json:"build_time"`
+	IPVersion    uint      `json:"ip_version"`
+	NodeCount    uint      `json:"node_count"`
+	Languages    []string  `json:"languages"`
+	Path         string    `json:"path,omitempty"`
+	Origin       DBOrigin  `json:"origin"`
+
+	// 以下字段仅在 Origin 为 downloaded 时有值
+	UpdateSource string    `json:"update_source,omitempty"` // 更新源, 如 github
+	Version      string    `json:"version,omitempty"`       // 更新源的版本标识, 如 release tag
+	UpdatedAt    time.Time `json:"updated_at,omitzero"`
+}
+
+// DatabaseMeta 返回已加载数据库的元数据, 按合并顺序排列; 已关闭的数据库不包含在内
+func (c *Client) DatabaseMeta() []DBMeta {
+	out := make([]DBMeta, 0, len(c.dbs))
+	for _, s := range c.dbs {
+		reader, release := s.acquire()
+		if reader == nil {
+			release()
+			continue
+		}
+		md := reader.Metadata
+		release()
+
+		path, defaultPath := s.path, s.defaultPath
+		if s.handle != nil {
+			path, defaultPath = s.handle.path, s.handle.defaultPath
+		}
+		m := DBMeta{
+			Kind:         s.kind,
+			DatabaseType: md.DatabaseType,
+			BuildEpoch:   md.BuildEpoch,
+			BuildTime:    md.BuildTime().UTC(),
+			IPVersion:    md.IPVersion,
+			NodeCount:    md.NodeCount,
+			Languages:    md.Languages,
+			Path:         path,
+		}
+
+		st, err := data.LoadUpdateState(path)
+		switch {
+		case path == "":
+			m.Origin = DBOriginReader
+		case err == nil && st.BuildEpoch == md.BuildEpoch:
+			m.Origin = DBOriginDownloaded
+			m.UpdateSource, m.Version, m.UpdatedAt = st.Source, st.Version, st.UpdatedAt
+		case defaultPath:
+			m.Origin = DBOriginEmbedded
+		default:
+			m.Origin = DBOriginFile
+		}
+		out = append(out, m)
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package ipinfo
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestDatabaseMeta(t *testing.T) {
+	path := copyCityDB(t)
+	cli, err := New(WithDBPaths(path))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	metas := cli.DatabaseMeta()
+	if len(metas) != 1 {
+		t.Fatalf("元数据数量错误: %d", len(metas))
+	}
+	m := metas[0]
+	if m.Kind != DBCity || m.DatabaseType != "GeoLite2-City" || m.Path != path || m.Origin != DBOriginFile {
+		t.Errorf("元数据错误: %+v", m)
+	}
+	if m.BuildEpoch == 0 || m.BuildTime.Unix() != int64(m.BuildEpoch) || m.IPVersion != 6 || m.NodeCount == 0 {
+		t.Errorf("元数据错误: %+v", m)
+	}
+
+	// 更新记录与当前数据库一致时视为下载
+	st := data.UpdateState{Source: "github", Version: "v1", BuildEpoch: m.BuildEpoch}
+	b, _ := json.Marshal(st)
+	if err := os.WriteFile(path+".state.json", b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = cli.DatabaseMeta()[0]
+	if m.Origin != DBOriginDownloaded || m.UpdateSource != "github" || m.Version != "v1" {
+		t.Errorf("下载的数据库元数据错误: %+v", m)
+	}
+
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	json.Unmarshal(out, &decoded)
+	if decoded["kind"] != "city" || decoded["origin"] != "downloaded" {
+		t.Errorf("JSON 序列化错误: %s", out)
+	}
+
+	// 记录过期(构建时间不一致)时不视为下载
+	st.BuildEpoch--
+	b, _ = json.Marshal(st)
+	os.WriteFile(path+".state.json", b, 0644)
+	if m := cli.DatabaseMeta()[0]; m.Origin != DBOriginFile || m.Version != "" {
+		t.Errorf("更新记录过期时来源错误: %+v", m)
+	}
+}
+
+func TestDatabaseMetaOrigin(t *testing.T) {
+	cli, err := New()
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+	if m := cli.DatabaseMeta(); len(m) == 0 || m[0].Origin != DBOriginEmbedded || m[0].Path == "" {
+		t.Errorf("默认数据库应为内置: %+v", m)
+	}
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cli2, err := New(WithDBReader(db))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli2.Close()
+	if m := cli2.DatabaseMeta(); len(m) == 0 || m[0].Origin != DBOriginReader || m[0].Path != "" {
+		t.Errorf("传入的阅读器来源错误: %+v", m)
+	}
+}
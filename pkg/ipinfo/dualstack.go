@@ -0,0 +1,148 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// getDualStackGeoIPData 分别强制 tcp4/tcp6 获取 IPv4 与 IPv6 出口, 并使用 MaxMind 各自定位
+func (c *Client) getDualStackGeoIPData(ctx context.Context, tr *trail) (IPData, error) {
+	var v4, v6 IPData
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		v4.IPv4 = c.fetchFamilyExitIP(ctx, c.ipv4Client, c.ipv4Providers, false)
+	}()
+	go func() {
+		defer wg.Done()
+		v6.IPv6 = c.fetchFamilyExitIP(ctx, c.ipv6Client, c.ipv6Providers, true)
+	}()
+	wg.Wait()
+
+	if v4.IPv4 == "" && v6.IPv6 == "" {
+		if ctx.Err() != nil {
+			return IPData{}, tr.lookupError(ErrCancelled, ctx.Err())
+		}
+		return IPData{}, tr.lookupError(ErrNoExitIP, nil)
+	}
+
+	// 各协议族独立定位
+	for _, info := range []*IPData{&v4, &v6} {
+		if info.IPv4 == "" && info.IPv6 == "" {
+			continue
+		}
+		if _, err := c.LookupGeoIPDataWithMMDB(info); err != nil {
+			slog.Debug(fmt.Sprintf("MaxMind 查询失败: %v", err))
+		}
+		if info.CountryCode != "" && !c.acceptCountry("maxmind", info.CountryCode) {
+			slog.Debug(fmt.Sprintf("MaxMind 国家代码 %s 被策略拒绝", info.CountryCode))
+			info.clearGeo()
+		}
+	}
+
+	// 以 IPv4 出口的位置为主, 其次为 IPv6
+	info := v4
+	if info.CountryCode == "" {
+		info = v6
+	}
+	info.IPv4 = v4.IPv4
+	info.IPv6 = v6.IPv6
+	info.IPv6CountryCode = v6.CountryCode
+	info.SplitCountry = v4.CountryCode != "" && v6.CountryCode != "" && v4.CountryCode != v6.CountryCode
+	if info.SplitCountry {
+		slog.Debug(fmt.Sprintf("双栈出口位于不同国家: IPv4=%s(%s) IPv6=%s(%s)", v4.IPv4, v4.CountryCode, v6.IPv6, v6.CountryCode))
+	}
+
+	c.CheckCDN(&info)
+	if info.CountryCode == "" {
+		return info, tr.lookupError(ErrNoGeo, nil)
+	}
+	return info, nil
+}
+
+// fetchFamilyExitIP 依次尝试指定协议族的提供者, 仅接受对应协议族的地址
+func (c *Client) fetchFamilyExitIP(ctx context.Context, hc *http.Client, providers []Provider, ipv6 bool) string {
+	for _, p := range c.rankProviders(providers) {
+		if ctx.Err() != nil {
+			return ""
+		}
+		temp, err := c.fetchExitIP(ctx, hc, p)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("双栈检测获取出口 IP 失败: %s, err: %v", p.Name(), err))
+			continue
+		}
+		if ipv6 && temp.IPv6 != "" {
+			return temp.IPv6
+		}
+		if !ipv6 && temp.IPv4 != "" {
+			return temp.IPv4
+		}
+	}
+	return ""
+}
+
+// familyHTTPClient 复制 http 客户端并在直连时强制使用指定网络(tcp4/tcp6)拨号;
+// 经代理的请求只能决定到代理的连接, 不强制协议族, 自定义拨号(可能为代理拨号)或非 *http.Transport 的
+// Transport 同样不强制, 均仅依赖协议族专用的 API 地址
+func familyHTTPClient(hc *http.Client, network string) *http.Client {
+	cp := *hc
+
+	var tr *http.Transport
+	switch t := hc.Transport.(type) {
+	case nil:
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		if t.DialContext != nil || t.Dial != nil || t.DialTLSContext != nil || t.DialTLS != nil {
+			return &cp
+		}
+		tr = t.Clone()
+	default:
+		return &cp
+	}
+
+	direct := tr.Clone()
+	direct.Proxy = nil
+	dial := direct.DialContext
+	if dial == nil {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		dial = dialer.DialContext
+	}
+	direct.DialContext = func(ctx context.Context, nw, addr string) (net.Conn, error) {
+		if strings.HasPrefix(nw, "tcp") {
+			nw = network
+		}
+		return dial(ctx, nw, addr)
+	}
+
+	if tr.Proxy == nil {
+		cp.Transport = direct
+	} else {
+		cp.Transport = &familyTransport{proxied: tr, direct: direct}
+	}
+	return &cp
+}
+
+// familyTransport 按请求是否经代理选择 Transport, 仅直连请求强制协议族
+type familyTransport struct {
+	proxied *http.Transport // 原 Transport, 保留代理设置
+	direct  *http.Transport // 强制协议族拨号, 不使用代理
+}
+
+func (t *familyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if u, err := t.proxied.Proxy(req); err != nil || u != nil {
+		return t.proxied.RoundTrip(req)
+	}
+	return t.direct.RoundTrip(req)
+}
+
+func (t *familyTransport) CloseIdleConnections() {
+	t.proxied.CloseIdleConnections()
+	t.direct.CloseIdleConnections()
+}
@@ -0,0 +1,147 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestGetDualStackGeoIPData(t *testing.T) {
+	srv4 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "45.65.122.98")
+	}))
+	defer srv4.Close()
+
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("本机不支持 IPv6 回环: %v", err)
+	}
+	srv6 := &httptest.Server{
+		Listener: ln,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "2a09:bac1:31e0:8::245:d4")
+		})},
+	}
+	srv6.Start()
+	defer srv6.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	// 强制 tcp4 的客户端无法访问仅监听 IPv6 的服务，反之亦然
+	cli, err := New(
+		WithDBReader(db),
+		WithDualStack([]string{srv6.URL, srv4.URL}, []string{srv4.URL, srv6.URL}),
+		WithAttemptTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := cli.GetGeoIPData(ctx)
+	if err != nil {
+		t.Fatalf("双栈检测失败: %v", err)
+	}
+	t.Logf("IPv4: %s(%s), IPv6: %s(%s), SplitCountry: %v", info.IPv4, info.CountryCode, info.IPv6, info.IPv6CountryCode, info.SplitCountry)
+	if info.IPv4 != "45.65.122.98" || info.IPv6 != "2a09:bac1:31e0:8::245:d4" {
+		t.Errorf("双栈出口地址错误: %+v", info)
+	}
+	if info.CountryCode == "" || info.IPv6CountryCode == "" {
+		t.Error("双栈出口未能分别定位")
+	}
+	if info.SplitCountry != (info.CountryCode != info.IPv6CountryCode) {
+		t.Error("SplitCountry 与国家代码不一致")
+	}
+}
+
+func TestDualStackCountryPolicy(t *testing.T) {
+	srv4 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "45.65.122.98")
+	}))
+	defer srv4.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	base := []Option{
+		WithDBReader(db),
+		WithDualStack([]string{srv4.URL}, []string{srv4.URL}),
+		WithAttemptTimeout(time.Second),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := newTestClient(t, base...).GetGeoIPData(ctx)
+	if err != nil {
+		t.Fatalf("双栈检测失败: %v", err)
+	}
+	mmCountry := info.CountryCode
+
+	// 双栈检测同样应用国家代码策略, 被拒绝的结果不应返回
+	info, err = newTestClient(t, append(base, WithRejectCountries(strings.ToLower(mmCountry)))...).GetGeoIPData(ctx)
+	if !errors.Is(err, ErrNoGeo) || info.CountryCode != "" || info.IPv4 != "45.65.122.98" {
+		t.Errorf("拒绝 %s 后应返回 ErrNoGeo 及出口 IP, got: %+v, err: %v", mmCountry, info, err)
+	}
+}
+
+func TestDualStackProxy(t *testing.T) {
+	// 仅监听 IPv4 的 HTTP 代理, 按请求的主机返回对应协议族的出口
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "v4.example":
+			fmt.Fprint(w, "45.65.122.98")
+		case "v6.example":
+			fmt.Fprint(w, "2a09:bac1:31e0:8::245:d4")
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	// 经代理时不强制到代理的连接使用 tcp6
+	cli, err := New(
+		WithDBReader(db),
+		WithHttpClient(&http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}),
+		WithDualStack([]string{"http://v4.example/"}, []string{"http://v6.example/"}),
+		WithAttemptTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := cli.GetGeoIPData(ctx)
+	if err != nil {
+		t.Fatalf("经代理的双栈检测失败: %v", err)
+	}
+	if info.IPv4 != "45.65.122.98" || info.IPv6 != "2a09:bac1:31e0:8::245:d4" {
+		t.Errorf("经代理的双栈出口地址错误: %+v", info)
+	}
+}
@@ -0,0 +1,91 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GetGeoIPData 的错误类别, 可使用 errors.Is 判断
+var (
+	ErrNoExitIP  = errors.New("所有 ipAPI 及 geoAPI 均未能获取到有效的IP地址,疑似网络断开")
+	ErrNoGeo     = errors.New("未能通过 MaxMind 或 geoAPI 获取到地理位置信息")
+	ErrCancelled = errors.New("获取出口 IP 已中止")
+)
+
+// Attempt 单次提供者请求记录
+type Attempt struct {
+	Provider   string        // 提供者名称
+	URL        string        // 请求地址
+	StatusCode int           // HTTP 状态码, 未收到响应时为 0
+	Duration   time.Duration // 请求耗时
+	Err        error         // 失败原因, 成功时为 nil
+}
+
+// LookupError GetGeoIPData 失败时返回的错误, 携带每个提供者的请求记录;
+// errors.Is 可同时匹配错误类别(ErrNoExitIP/ErrNoGeo/ErrCancelled)及底层原因(如 context.DeadlineExceeded)
+type LookupError struct {
+	Kind     error     // 错误类别
+	Cause    error     // 底层原因, 可能为 nil
+	Attempts []Attempt // 按完成顺序排列的请求记录
+}
+
+func (e *LookupError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+	if e.Cause != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Cause.Error())
+	}
+	failed := 0
+	for _, a := range e.Attempts {
+		if a.Err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(&b, " (共请求 %d 次, 失败 %d 次)", len(e.Attempts), failed)
+	return b.String()
+}
+
+func (e *LookupError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Cause}
+}
+
+// trail 并发安全的请求记录
+type trail struct {
+	mu       sync.Mutex
+	attempts []Attempt
+}
+
+type trailKey struct{}
+
+// withTrail 在 ctx 中挂载请求记录, 供 fetchProvider 写入
+func withTrail(ctx context.Context) (context.Context, *trail) {
+	t := &trail{}
+	return context.WithValue(ctx, trailKey{}, t), t
+}
+
+// addAttempt 向 ctx 中的请求记录追加一条, ctx 未挂载记录时忽略
+func addAttempt(ctx context.Context, a Attempt) {
+	t, ok := ctx.Value(trailKey{}).(*trail)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.attempts = append(t.attempts, a)
+	t.mu.Unlock()
+}
+
+// lookupError 根据请求记录生成错误
+func (t *trail) lookupError(kind, cause error) *LookupError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &LookupError{Kind: kind, Cause: cause, Attempts: slices.Clone(t.attempts)}
+}
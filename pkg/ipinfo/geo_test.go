@@ -3,9 +3,12 @@ package ipinfo
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	// "crypto/tls"
 	"net/http"
+	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -96,10 +99,11 @@ func TestFetchExitIPandLookupGeoDB(t *testing.T) {
 	}
 	defer cli.Close()
 
-	total := len(cli.ipAPIs)
+	total := len(cli.ipProviders)
 	success := 0
 
-	for _, url := range cli.ipAPIs {
+	for _, p := range cli.ipProviders {
+		url := p.URL()
 		ipData, err := cli.FetchExitIP(url)
 		if err != nil {
 			t.Logf("获取 %s 时出错: %v", url, err)
@@ -156,10 +160,11 @@ func TestFetchExitIP(t *testing.T) {
 	}
 	defer cli.Close()
 
-	total := len(cli.ipAPIs)
+	total := len(cli.ipProviders)
 	success := 0
 
-	for _, url := range cli.ipAPIs {
+	for _, p := range cli.ipProviders {
+		url := p.URL()
 		ipData, err := cli.FetchExitIP(url)
 		if err != nil {
 			t.Logf("获取 %s 时出错: %v", url, err)
@@ -215,6 +220,38 @@ func TestLookupGeoIPDataWithMMDB(t *testing.T) {
 	}
 }
 
+func TestLookupASNWithMMDB(t *testing.T) {
+	asnDB, err := data.OpenMaxMindASNDB("")
+	if errors.Is(err, data.ErrASNDBUnavailable) {
+		t.Skip("ASN 数据库不可用, 跳过")
+	}
+	if err != nil {
+		t.Fatalf("打开 ASN 数据库失败: %v", err)
+	}
+	defer asnDB.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithDBReader(db), WithASNDBReader(asnDB))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	ipData := CreateIPDataFromIP("1.1.1.1")
+	if _, err := cli.LookupGeoIPDataWithMMDB(ipData); err != nil {
+		t.Fatalf("获取 MaxMind 数据失败: %v", err)
+	}
+	t.Logf("ASN: %d, Org: %s, Network: %s", ipData.ASN, ipData.ASOrganization, ipData.Network)
+	if ipData.ASN == 0 || ipData.ASOrganization == "" || ipData.Network == "" {
+		t.Error("未能获取有效 ASN 信息")
+	}
+}
+
 func TestFetchGeoIPData(t *testing.T) {
 	// 创建支持不安全 TLS 的客户端（仅用于测试）
 	// tr := &http.Transport{
@@ -247,7 +284,8 @@ func TestFetchGeoIPData(t *testing.T) {
 	}
 	defer cli.Close()
 
-	for _, url := range cli.geoAPIs {
+	for _, p := range cli.geoProviders {
+		url := p.URL()
 		geo, err := cli.FetchGeoIPData(url)
 		if err != nil {
 			t.Logf("[FAIL] URL: %s, err: %v", url, err)
@@ -336,3 +374,185 @@ func TestGetIP_HTML(t *testing.T) {
 		t.Error("HTML IP 解析失败")
 	}
 }
+
+func TestGetGeoIPDataCancel(t *testing.T) {
+	// 模拟一个迟迟不返回的 ipAPI
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(
+		WithDBReader(db),
+		WithIPAPIs(srv.URL, srv.URL, srv.URL),
+		WithGeoAPIs(srv.URL),
+		WithAttemptTimeout(3*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = cli.GetGeoIPData(ctx)
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatal("ctx 超时后应返回错误")
+	}
+	if !errors.Is(err, ErrCancelled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("错误应为 ErrCancelled 并包含 ctx 超时原因, got: %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("ctx 超时后未能及时中止, 耗时 %v", elapsed)
+	}
+}
+
+func TestGetGeoIPDataLookupError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(
+		WithDBReader(db),
+		WithIPAPIs(srv.URL+"/ip"),
+		WithGeoAPIs(srv.URL+"/geo"),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	info, err := cli.GetGeoIPData(context.Background())
+	if !errors.Is(err, ErrNoExitIP) || errors.Is(err, ErrCancelled) {
+		t.Fatalf("错误类别应为 ErrNoExitIP, got: %v", err)
+	}
+	if info.IPv4 != "" || info.IPv6 != "" {
+		t.Errorf("ErrNoExitIP 时不应返回部分数据: %+v", info)
+	}
+
+	var lookupErr *LookupError
+	if !errors.As(err, &lookupErr) {
+		t.Fatalf("错误应为 *LookupError, got: %T", err)
+	}
+	if len(lookupErr.Attempts) != 2 {
+		t.Fatalf("请求记录数量错误: %+v", lookupErr.Attempts)
+	}
+	for _, a := range lookupErr.Attempts {
+		if a.StatusCode != http.StatusTooManyRequests || a.Err == nil || a.URL == "" {
+			t.Errorf("请求记录不完整: %+v", a)
+		}
+	}
+}
+
+// newTestClient 创建客户端, 测试结束时关闭
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+	cli, err := New(opts...)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
+
+func TestCountryPolicy(t *testing.T) {
+	ipSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("45.65.122.98"))
+	}))
+	defer ipSrv.Close()
+	geoSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ip":"45.65.122.98","country_code":"JP"}`))
+	}))
+	defer geoSrv.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	base := []Option{WithDBReader(db), WithIPAPIs(ipSrv.URL), WithGeoAPIs(geoSrv.URL)}
+
+	// 默认接受 MaxMind 结果
+	cli := newTestClient(t, base...)
+	info, err := cli.GetGeoIPData(context.Background())
+	if err != nil {
+		t.Fatalf("获取 GeoIP 数据失败: %v", err)
+	}
+	mmCountry := info.CountryCode
+
+	// 拒绝 MaxMind 给出的国家后回退到 geoAPI
+	cli = newTestClient(t, append(base, WithRejectCountries(strings.ToLower(mmCountry)))...)
+	info, err = cli.GetGeoIPData(context.Background())
+	if err != nil || info.CountryCode != "JP" {
+		t.Errorf("拒绝 %s 后应回退到 geoAPI, got: %+v, err: %v", mmCountry, info, err)
+	}
+
+	// 全部来源均被拒绝时返回 ErrNoGeo 及出口 IP, 不返回被拒绝的位置信息
+	cli = newTestClient(t, append(base, WithCountryPolicy(func(source, countryCode string) bool { return false }))...)
+	info, err = cli.GetGeoIPData(context.Background())
+	if !errors.Is(err, ErrNoGeo) || info.IPv4 != "45.65.122.98" {
+		t.Errorf("全部拒绝时应返回 ErrNoGeo 及出口 IP, got: %+v, err: %v", info, err)
+	}
+	if info.CountryCode != "" || info.City != "" {
+		t.Errorf("全部拒绝时不应返回被拒绝的位置信息: %+v", info)
+	}
+}
+
+func TestMaxMindIgnoresProviderGeo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ip":"45.65.122.98","country_code":"JP","asn":64500}`))
+	}))
+	defer srv.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	// ipAPI 返回的位置信息不应混入 MaxMind 的结果
+	var sources []string
+	cli, err := New(
+		WithDBReader(db),
+		WithProviders(&HTTPProvider{Endpoint: srv.URL, Caps: CapExitIP | CapCountry | CapASN, Parser: jsonParser(snakeCaseKeys)}),
+		WithCountryPolicy(func(source, countryCode string) bool {
+			sources = append(sources, source+"="+countryCode)
+			return true
+		}),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	info, err := cli.GetGeoIPData(context.Background())
+	if err != nil {
+		t.Fatalf("获取 GeoIP 数据失败: %v", err)
+	}
+	if info.CountryCode == "JP" || info.ASN != 0 {
+		t.Errorf("ipAPI 的位置信息混入 MaxMind 结果: %+v", info)
+	}
+	if len(sources) != 1 || strings.HasSuffix(sources[0], "=JP") {
+		t.Errorf("策略收到的 MaxMind 结果错误: %v", sources)
+	}
+}
@@ -0,0 +1,198 @@
+package ipinfo
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 3                // 连续失败多少次后熔断
+	defaultCooldown         = 30 * time.Second // 首次熔断时长, 此后每次翻倍
+	maxCooldown             = 10 * time.Minute // 熔断时长上限
+	latencyEWMAAlpha        = 0.3              // 延迟 EWMA 平滑系数
+	unknownLatency          = time.Second      // 无成功样本时的假定延迟
+)
+
+// ProviderStats 提供者的健康统计
+type ProviderStats struct {
+	Name                string
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	SuccessRate         float64       // 平滑后的成功率
+	LatencyEWMA         time.Duration // 成功请求延迟的指数加权平均
+	BenchedUntil        time.Time     // 熔断截止时间, 零值表示未熔断
+	Score               float64       // 排序得分, 越高越优先
+}
+
+// providerHealth 单个提供者的健康状态
+type providerHealth struct {
+	successes    int
+	failures     int
+	consecutive  int
+	latency      time.Duration
+	benches      int // 连续熔断次数, 用于退避
+	benchedUntil time.Time
+}
+
+// score 平滑成功率 / (1 + 延迟秒数)
+func (h *providerHealth) score() float64 {
+	rate := float64(h.successes+1) / float64(h.successes+h.failures+2)
+	latency := h.latency
+	if latency == 0 {
+		latency = unknownLatency
+	}
+	return rate / (1 + latency.Seconds())
+}
+
+// healthTracker 按提供者记录成功率、延迟及熔断状态
+type healthTracker struct {
+	mu        sync.Mutex
+	stats     map[string]*providerHealth
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func newHealthTracker(threshold int, cooldown time.Duration) *healthTracker {
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultCooldown
+	}
+	return &healthTracker{
+		stats:     make(map[string]*providerHealth),
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// get 获取或创建提供者状态, 调用方需持有锁
+func (t *healthTracker) get(name string) *providerHealth {
+	h, ok := t.stats[name]
+	if !ok {
+		h = &providerHealth{}
+		t.stats[name] = h
+	}
+	return h
+}
+
+// record 记录一次请求结果
+func (t *healthTracker) record(name string, latency time.Duration, ok bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.get(name)
+	if ok {
+		h.successes++
+		h.consecutive = 0
+		h.benches = 0
+		h.benchedUntil = time.Time{}
+		if h.latency == 0 {
+			h.latency = latency
+		} else {
+			h.latency = time.Duration(latencyEWMAAlpha*float64(latency) + (1-latencyEWMAAlpha)*float64(h.latency))
+		}
+		return
+	}
+
+	h.failures++
+	h.consecutive++
+	if h.consecutive >= t.threshold {
+		// 熔断时长指数退避, 熔断结束后的首次请求失败会再次熔断
+		cooldown := t.cooldown << h.benches
+		if cooldown > maxCooldown || cooldown <= 0 {
+			cooldown = maxCooldown
+		}
+		h.benches++
+		h.benchedUntil = t.now().Add(cooldown)
+		slog.Debug(fmt.Sprintf("%s 连续失败 %d 次，熔断 %v", name, h.consecutive, cooldown))
+	}
+}
+
+// benched 是否处于熔断期, 调用方需持有锁
+func (t *healthTracker) benched(name string, now time.Time) bool {
+	h, ok := t.stats[name]
+	return ok && now.Before(h.benchedUntil)
+}
+
+// snapshot 返回全部提供者的统计, 按得分降序
+func (t *healthTracker) snapshot() []ProviderStats {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	out := make([]ProviderStats, 0, len(t.stats))
+	for name, h := range t.stats {
+		s := ProviderStats{
+			Name:                name,
+			Successes:           h.successes,
+			Failures:            h.failures,
+			ConsecutiveFailures: h.consecutive,
+			SuccessRate:         float64(h.successes+1) / float64(h.successes+h.failures+2),
+			LatencyEWMA:         h.latency,
+			Score:               h.score(),
+		}
+		if now.Before(h.benchedUntil) {
+			s.BenchedUntil = h.benchedUntil
+		}
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b ProviderStats) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Name, b.Name))
+	})
+	return out
+}
+
+// rankByHealth 按健康得分排序, 得分相同的保持随机顺序; 熔断中的提供者被剔除, 若全部熔断则按得分全部返回
+func rankByHealth[T any](t *healthTracker, items []T, name func(T) string) []T {
+	out := shuffle(items)
+	if t == nil {
+		return out
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	scores := make(map[string]float64, len(out))
+	for _, it := range out {
+		n := name(it)
+		if h, ok := t.stats[n]; ok {
+			scores[n] = h.score()
+		} else {
+			scores[n] = (&providerHealth{}).score()
+		}
+	}
+	slices.SortStableFunc(out, func(a, b T) int {
+		return cmp.Compare(scores[name(b)], scores[name(a)])
+	})
+
+	active := slices.DeleteFunc(slices.Clone(out), func(it T) bool { return t.benched(name(it), now) })
+	if len(active) == 0 {
+		return out
+	}
+	return active
+}
+
+// ProviderStats 返回当前客户端各提供者(含 Cloudflare trace 地址)的健康统计, 按得分降序
+func (c *Client) ProviderStats() []ProviderStats {
+	return c.health.snapshot()
+}
+
+// rankProviders 按健康得分排序提供者
+func (c *Client) rankProviders(providers []Provider) []Provider {
+	return rankByHealth(c.health, providers, Provider.Name)
+}
@@ -0,0 +1,109 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestHealthTrackerBench(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tr := newHealthTracker(2, 10*time.Second)
+	tr.now = func() time.Time { return now }
+	id := func(s string) string { return s }
+
+	tr.record("fast", 100*time.Millisecond, true)
+	tr.record("slow", 2*time.Second, true)
+	tr.record("dead", 0, false)
+
+	ranked := rankByHealth(tr, []string{"dead", "slow", "fast", "new"}, id)
+	if ranked[0] != "fast" {
+		t.Errorf("得分最高的提供者应排在首位: %v", ranked)
+	}
+
+	// 连续失败达到阈值后熔断
+	tr.record("dead", 0, false)
+	ranked = rankByHealth(tr, []string{"dead", "slow", "fast"}, id)
+	for _, n := range ranked {
+		if n == "dead" {
+			t.Errorf("熔断中的提供者不应被使用: %v", ranked)
+		}
+	}
+	stats := tr.snapshot()
+	if stats[len(stats)-1].Name != "dead" || stats[len(stats)-1].BenchedUntil.IsZero() {
+		t.Errorf("熔断状态未在统计中体现: %+v", stats)
+	}
+
+	// 全部熔断时仍返回全部提供者
+	if ranked = rankByHealth(tr, []string{"dead"}, id); len(ranked) != 1 {
+		t.Errorf("全部熔断时应返回全部提供者: %v", ranked)
+	}
+
+	// 熔断结束后恢复使用, 再次失败则熔断时长翻倍
+	now = now.Add(11 * time.Second)
+	if ranked = rankByHealth(tr, []string{"dead", "fast"}, id); len(ranked) != 2 {
+		t.Errorf("熔断结束后应恢复使用: %v", ranked)
+	}
+	tr.record("dead", 0, false)
+	if until := tr.stats["dead"].benchedUntil; !until.Equal(now.Add(20 * time.Second)) {
+		t.Errorf("熔断时长未翻倍: %v", until.Sub(now))
+	}
+
+	// 成功后清除熔断
+	tr.record("dead", 100*time.Millisecond, true)
+	if tr.stats["dead"].consecutive != 0 || !tr.stats["dead"].benchedUntil.IsZero() {
+		t.Error("成功后未清除熔断状态")
+	}
+}
+
+func TestProviderStats(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "45.65.122.98")
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(
+		WithDBReader(db),
+		WithProviders(
+			&HTTPProvider{Label: "ok", Endpoint: ok.URL, Caps: CapExitIP},
+			&HTTPProvider{Label: "bad", Endpoint: bad.URL, Caps: CapExitIP},
+		),
+		WithCircuitBreaker(1, time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	for range 5 {
+		if _, err := cli.GetGeoIPData(context.Background()); err != nil {
+			t.Fatalf("获取出口 IP 失败: %v", err)
+		}
+	}
+
+	stats := cli.ProviderStats()
+	if len(stats) == 0 || stats[0].Name != "ok" || stats[0].Successes != 5 {
+		t.Fatalf("统计结果错误: %+v", stats)
+	}
+	// bad 最多被尝试一次即熔断
+	for _, s := range stats {
+		if s.Name == "bad" && (s.Failures != 1 || s.BenchedUntil.IsZero()) {
+			t.Errorf("失败提供者未被熔断: %+v", s)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package ipinfo
+
+import (
+	"cmp"
+	"errors"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// DBType MaxMind 格式数据库类型, 由元数据 database_type 自动识别;
+// 查询时按类型顺序依次合并, 靠后数据库的非空字段覆盖靠前的
+type DBType int
+
+const (
+	DBCountry     DBType = iota // GeoIP2/GeoLite2/DB-IP Country
+	DBCity                      // GeoIP2/GeoLite2/DB-IP City
+	DBASN                       // GeoLite2-ASN / DB-IP ASN
+	DBAnonymousIP               // GeoIP2-Anonymous-IP
+	DBCustom                    // 其他数据库, 作为覆盖层最后合并
+)
+
+func (t DBType) String() string {
+	switch t {
+	case DBCountry:
+		return "country"
+	case DBCity:
+		return "city"
+	case DBASN:
+		return "asn"
+	case DBAnonymousIP:
+		return "anonymous-ip"
+	default:
+		return "custom"
+	}
+}
+
+// MarshalText 以类型名称序列化
+func (t DBType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// DetectDBType 根据元数据中的 database_type 识别数据库类型
+func DetectDBType(db *maxminddb.Reader) DBType {
+	t := db.Metadata.DatabaseType
+	switch {
+	case strings.Contains(t, "Anonymous-IP"):
+		return DBAnonymousIP
+	case strings.Contains(t, "ASN"):
+		return DBASN
+	case strings.Contains(t, "City"):
+		return DBCity
+	case strings.Contains(t, "Country"):
+		return DBCountry
+	default:
+		return DBCustom
+	}
+}
+
+// mmdbSource 已加载的数据库
+type mmdbSource struct {
+	reader      *maxminddb.Reader
+	handle      *ReloadableDB // 可热替换的数据库, 非空时忽略 reader
+	kind        DBType
+	own         bool   // 由客户端打开, Close 时关闭
+	path        string // 由客户端按路径打开时的文件路径
+	defaultPath bool   // 未指定路径, 使用数据目录中的数据库
+}
+
+// acquire 获取查询使用的阅读器, 用完后调用 release
+func (s mmdbSource) acquire() (reader *maxminddb.Reader, release func()) {
+	if s.handle == nil {
+		return s.reader, func() {}
+	}
+	r := s.handle.acquire()
+	if r == nil {
+		return nil, func() {}
+	}
+	return r.reader, func() { r.release() }
+}
+
+// close 关闭数据库
+func (s mmdbSource) close() error {
+	if s.handle != nil {
+		return s.handle.Close()
+	}
+	return s.reader.Close()
+}
+
+// mmdbRecord 各类数据库字段的并集, 不存在的字段解码为零值
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+		TimeZone  string  `maxminddb:"time_zone"`
+		// AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+	} `maxminddb:"location"`
+
+	ASN            uint   `maxminddb:"autonomous_system_number"`
+	ASOrganization string `maxminddb:"autonomous_system_organization"`
+
+	IsAnonymous        bool `maxminddb:"is_anonymous"`
+	IsAnonymousVPN     bool `maxminddb:"is_anonymous_vpn"`
+	IsHostingProvider  bool `maxminddb:"is_hosting_provider"`
+	IsPublicProxy      bool `maxminddb:"is_public_proxy"`
+	IsResidentialProxy bool `maxminddb:"is_residential_proxy"`
+	IsTorExitNode      bool `maxminddb:"is_tor_exit_node"`
+}
+
+// mergeInto 将非空字段写入 info; 网段仅取自地理位置及 ASN 数据库
+func (r *mmdbRecord) mergeInto(info *IPData, kind DBType, prefix netip.Prefix) {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+
+	set(&info.CountryCode, strings.ToUpper(r.Country.ISOCode))
+	set(&info.CountryName, r.Country.Names["en"])
+	set(&info.ContinentCode, strings.ToUpper(r.Continent.Code))
+	set(&info.City, r.City.Names["en"])
+	if len(r.Subdivisions) > 0 {
+		set(&info.Region, r.Subdivisions[0].Names["en"])
+		set(&info.RegionCode, strings.ToUpper(r.Subdivisions[0].ISOCode))
+	}
+	set(&info.PostalCode, r.Postal.Code)
+	if r.Location.Latitude != 0 || r.Location.Longitude != 0 {
+		info.Latitude = r.Location.Latitude
+		info.Longitude = r.Location.Longitude
+	}
+	set(&info.TimeZone, r.Location.TimeZone)
+
+	if r.ASN != 0 {
+		info.ASN = r.ASN
+	}
+	set(&info.ASOrganization, r.ASOrganization)
+
+	info.IsAnonymous = info.IsAnonymous || r.IsAnonymous
+	info.IsAnonymousVPN = info.IsAnonymousVPN || r.IsAnonymousVPN
+	info.IsHostingProvider = info.IsHostingProvider || r.IsHostingProvider
+	info.IsPublicProxy = info.IsPublicProxy || r.IsPublicProxy
+	info.IsResidentialProxy = info.IsResidentialProxy || r.IsResidentialProxy
+	info.IsTorExitNode = info.IsTorExitNode || r.IsTorExitNode
+
+	if kind <= DBASN && prefix.IsValid() {
+		info.Network = prefix.String()
+	}
+}
+
+// addDB 识别类型并加入数据库列表
+func (c *Client) addDB(db *maxminddb.Reader, own bool) {
+	c.dbs = append(c.dbs, mmdbSource{reader: db, kind: DetectDBType(db), own: own})
+}
+
+// addReloadableDB 识别类型并加入可热替换的数据库
+func (c *Client) addReloadableDB(d *ReloadableDB, own bool) error {
+	r := d.acquire()
+	if r == nil {
+		return ErrDBClosed
+	}
+	defer r.release()
+	c.dbs = append(c.dbs, mmdbSource{handle: d, kind: DetectDBType(r.reader), own: own})
+	return nil
+}
+
+// ReloadDatabases 重新加载全部可热替换的数据库(按路径打开的数据库及内置数据库),
+// 进行中的查询继续使用旧数据库; 部分失败时保留对应的原数据库并返回错误
+func (c *Client) ReloadDatabases() error {
+	var errs []error
+	for _, s := range c.dbs {
+		if s.handle != nil {
+			errs = append(errs, s.handle.Reload())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasDB 是否已加载指定类型的数据库
+func (c *Client) hasDB(kinds ...DBType) bool {
+	return slices.ContainsFunc(c.dbs, func(s mmdbSource) bool { return slices.Contains(kinds, s.kind) })
+}
+
+// sortDBs 按合并顺序排列, 同类型保持传入顺序
+func (c *Client) sortDBs() {
+	slices.SortStableFunc(c.dbs, func(a, b mmdbSource) int { return cmp.Compare(a.kind, b.kind) })
+}
+
+// Databases 返回已加载数据库的类型, 按合并顺序排列
+func (c *Client) Databases() []DBType {
+	out := make([]DBType, len(c.dbs))
+	for i, s := range c.dbs {
+		out[i] = s.kind
+	}
+	return out
+}
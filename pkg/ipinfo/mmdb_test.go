@@ -0,0 +1,73 @@
+package ipinfo
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestMMDBRecordMerge(t *testing.T) {
+	var city, asn, anon, overlay mmdbRecord
+	city.Country.ISOCode = "us"
+	city.City.Names = map[string]string{"en": "Los Angeles"}
+	city.Location.TimeZone = "America/Los_Angeles"
+	asn.ASN = 13335
+	asn.ASOrganization = "CLOUDFLARENET"
+	anon.IsAnonymousVPN = true
+	overlay.Country.ISOCode = "hk"
+
+	info := &IPData{}
+	city.mergeInto(info, DBCity, netip.MustParsePrefix("1.1.0.0/16"))
+	asn.mergeInto(info, DBASN, netip.MustParsePrefix("1.1.1.0/24"))
+	anon.mergeInto(info, DBAnonymousIP, netip.MustParsePrefix("1.0.0.0/8"))
+	overlay.mergeInto(info, DBCustom, netip.MustParsePrefix("1.1.1.1/32"))
+
+	if info.CountryCode != "HK" {
+		t.Errorf("覆盖库应覆盖国家代码, got %q", info.CountryCode)
+	}
+	if info.City != "Los Angeles" || info.TimeZone != "America/Los_Angeles" {
+		t.Errorf("空字段不应覆盖已有值: %+v", info)
+	}
+	if info.ASN != 13335 || info.ASOrganization != "CLOUDFLARENET" {
+		t.Errorf("ASN 合并错误: %+v", info)
+	}
+	if !info.IsAnonymousVPN {
+		t.Error("匿名 IP 标记未合并")
+	}
+	if info.Network != "1.1.1.0/24" {
+		t.Errorf("网段应取自 ASN 数据库, got %q", info.Network)
+	}
+}
+
+func TestWithDBReaders(t *testing.T) {
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if kind := DetectDBType(db); kind != DBCity {
+		t.Errorf("数据库类型识别错误: %s (%s)", kind, db.Metadata.DatabaseType)
+	}
+
+	cli, err := New(WithDBReaders(db))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	// 已提供 City 数据库时不再加载内置数据库
+	if got := cli.Databases(); !slices.Equal(got, []DBType{DBCity}) {
+		t.Errorf("已加载数据库错误: %v", got)
+	}
+
+	ipData := CreateIPDataFromIP("1.1.1.1")
+	if _, err := cli.LookupGeoIPDataWithMMDB(ipData); err != nil {
+		t.Fatalf("获取 MaxMind 数据失败: %v", err)
+	}
+	if ipData.CountryCode == "" {
+		t.Error("未能获取有效国家代码")
+	}
+}
@@ -1,12 +1,19 @@
 package ipinfo
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/oschwald/maxminddb-golang/v2"
+	"github.com/sinspired/checkip/internal/config"
 	"github.com/sinspired/checkip/internal/data"
 )
 
@@ -15,8 +22,11 @@ type IPData struct {
 	IPv4          string
 	IPv6          string
 	IsCDN         bool
-	CountryCode   string // 国家代码（ISO）
-	CountryName   string // 国家英文全称
+	CDN           string   // 命中的 CDN 名称
+	CDNPrefix     string   // 命中的 CDN 网段
+	WARP          WARPMode // WARP 出口类型, 非 WARP 出口时为空
+	CountryCode   string   // 国家代码（ISO）
+	CountryName   string   // 国家英文全称
 	ContinentCode string
 	City          string
 	Region        string // 第一层行政区名称（省/州）
@@ -26,32 +36,100 @@ type IPData struct {
 	TimeZone  string
 	Latitude  float64
 	Longitude float64
+
+	ASN            uint   // 自治系统编号
+	ASOrganization string // 自治系统所属组织
+	Network        string // 数据库中匹配到的网段
+
+	// 匿名 IP 数据库标记
+	IsAnonymous        bool
+	IsAnonymousVPN     bool
+	IsHostingProvider  bool
+	IsPublicProxy      bool
+	IsResidentialProxy bool
+	IsTorExitNode      bool
+
+	IPv6CountryCode string // 双栈检测时 IPv6 出口的国家代码
+	SplitCountry    bool   // 双栈检测时 IPv4 与 IPv6 出口位于不同国家
+
+	ObservedIPs []string // 共识模式下观测到的全部出口 IP, 按票数降序
+	Agreement   float64  // 共识模式下多数 IP 的得票率, 小于 1 表示存在分流或多出口
 }
 
 // CFProxyInfo 存储 cloudflare CDN信息
 type CFProxyInfo struct {
-	isCFProxy bool   // 是否 Cloudflare 代理
-	exitLoc   string // 出口 IP 的地理位置
-	cfLoc     string // Cloudflare 代理 IP 的地理位置
+	isCFProxy bool     // 是否 Cloudflare 代理
+	exitLoc   string   // 出口 IP 的地理位置
+	cfLoc     string   // Cloudflare 代理 IP 的地理位置
+	warp      WARPMode // WARP 出口类型
+	colo      Colo     // 服务的 Cloudflare 数据中心
+}
+
+// Colo 服务当前出口的 Cloudflare 数据中心, 未请求 trace 或 trace 无 colo 字段时为零值
+func (p CFProxyInfo) Colo() Colo {
+	return p.colo
 }
 
 // IP 信息检测客户端
 type Client struct {
-	httpClient *http.Client      // 指定 http 客户端
-	mmdb       *maxminddb.Reader // 指定 MaxMind Geo 数据库
+	httpClient *http.Client // 指定 http 客户端
+	dbs        []mmdbSource // MaxMind 格式数据库, 按合并顺序排列
+
+	ipProviders  []Provider // 指定当前客户端获取出口 IP 的提供者
+	geoProviders []Provider // 指定当前客户端获取出口 GeoIP 的提供者
+
+	attemptTimeout time.Duration // 单个 API 请求的超时时间
+	totalTimeout   time.Duration // GetGeoIPData 的总超时预算, 0 表示仅受 ctx 控制
+
+	raceParallel int           // 竞速模式首批并发数, 0 表示顺序尝试
+	raceStagger  time.Duration // 竞速模式后续 API 的错峰启动间隔
+
+	quorum int // 共识模式需要的有效应答数, 0 表示关闭
+
+	countryPolicy CountryPolicy // 国家代码接受策略, 为空时全部接受
+
+	health           *healthTracker // 提供者健康统计, 用于排序与熔断
+	failureThreshold int            // 连续失败多少次后熔断
+	cooldown         time.Duration  // 首次熔断时长
 
-	ipAPIs  []string // 指定当前客户端获取出口 IP 的API
-	geoAPIs []string // 指定当前客户端获取出口 GeoIP 的API
+	dualStack     bool         // 双栈检测模式
+	ipv4Providers []Provider   // 双栈检测时仅返回 IPv4 的提供者
+	ipv6Providers []Provider   // 双栈检测时仅返回 IPv6 的提供者
+	ipv4Client    *http.Client // 强制 tcp4 拨号的 http 客户端
+	ipv6Client    *http.Client // 强制 tcp6 拨号的 http 客户端
+
+	coloTag      bool          // 标签中附加 Cloudflare 数据中心代码
+	tagFormatter *TagFormatter // 自定义标签模板, 为空时使用内置模板
+
+	probes []Probe // Analyze 时并发执行的服务可达性探测
+
+	cdnBase      *CDNRegistry                // 指定的 CDN 注册表, 为空时使用内置注册表
+	cdn          atomic.Pointer[CDNRegistry] // 合并网段文件后生效的注册表, 为空时使用 cdnBase
+	cdnProviders []CDNProvider               // 额外注册的 CDN
+	cdnFiles     []cdnFile                   // CDN 网段文件, 重新加载时再次读取
+	cdnReload    time.Duration               // 网段文件检查间隔, 0 表示不自动重新加载
+	cdnBaseGen   atomic.Uint64               // 构建时基础注册表的版本
+	cdnStop      chan struct{}
+	closeOnce    sync.Once
 
 	// internal
-	dbPath  string // 自定义数据库路径
-	ownMMDB bool
+	dbPaths []string // 待打开的数据库路径, 延迟到 New 打开
+
+	useASN  bool   // 是否加载 ASN 数据库
+	asnPath string // ASN 数据库路径, 为空时使用默认位置
 }
 
 // 客户端设置
 type Option func(*Client) error
 
-const defaultHTTPTimeout = 10 * time.Second
+// CountryPolicy 判断某一来源(maxmind 或 geoAPI 提供者名称)给出的国家代码是否可接受,
+// 不接受时回退到下一个来源
+type CountryPolicy func(source, countryCode string) bool
+
+const (
+	defaultHTTPTimeout    = 10 * time.Second
+	defaultAttemptTimeout = 6000 * time.Millisecond
+)
 
 // 默认 IPAPIs
 var defaultIPAPIs = []string{
@@ -86,25 +164,132 @@ func WithHttpClient(hc *http.Client) Option {
 
 // 指定 MaxMind 格式的数据库路径,默认为内置数据库
 func WithDBPath(path string) Option {
+	return WithDBPaths(path)
+}
+
+// 指定多个 MaxMind 格式的数据库路径, 类型由元数据自动识别, 查询结果按 DBType 顺序合并;
+// 未指定 City/Country 数据库时仍加载内置数据库
+func WithDBPaths(paths ...string) Option {
 	return func(c *Client) error {
-		if path == "" {
-			return fmt.Errorf("mmdb path is empty")
+		for _, path := range paths {
+			if path == "" {
+				return fmt.Errorf("mmdb path is empty")
+			}
 		}
-		c.dbPath = path
-		// 延迟到 New 打开，避免后续选项覆盖导致泄露
+		// 延迟到 New 打开，避免后续选项出错导致泄露
+		c.dbPaths = append(c.dbPaths, paths...)
 		return nil
 	}
 }
 
 // 指定 MaxMind 数据库阅读器,默认为内置阅读器
 func WithDBReader(db *maxminddb.Reader) Option {
+	return WithDBReaders(db)
+}
+
+// 指定多个 MaxMind 格式数据库阅读器, 类型由元数据自动识别, 查询结果按 DBType 顺序合并;
+// 阅读器由调用方负责关闭
+func WithDBReaders(dbs ...*maxminddb.Reader) Option {
+	return func(c *Client) error {
+		for _, db := range dbs {
+			if db == nil {
+				return fmt.Errorf("mmdb reader is nil")
+			}
+		}
+		for _, db := range dbs {
+			c.addDB(db, false)
+		}
+		return nil
+	}
+}
+
+// 启用 ASN 数据库, path 为空时使用数据目录中的 GeoLite2-ASN.mmdb 或内置数据库(不可用时忽略);
+// 启用后 IPData 会包含 ASN、组织及匹配网段
+func WithASNDB(path string) Option {
+	return func(c *Client) error {
+		c.useASN = true
+		c.asnPath = path
+		return nil
+	}
+}
+
+// 指定可热替换的 MaxMind 数据库, 调用 ReloadableDB.Reload 或 Client.ReloadDatabases 后立即生效;
+// 数据库由调用方负责关闭
+func WithReloadableDB(db *ReloadableDB) Option {
 	return func(c *Client) error {
 		if db == nil {
-			return fmt.Errorf("mmdb reader is nil")
+			return fmt.Errorf("reloadable db is nil")
+		}
+		return c.addReloadableDB(db, false)
+	}
+}
+
+// 指定 MaxMind ASN 数据库阅读器
+func WithASNDBReader(db *maxminddb.Reader) Option {
+	return func(c *Client) error {
+		if db == nil {
+			return fmt.Errorf("asn mmdb reader is nil")
+		}
+		if kind := DetectDBType(db); kind != DBASN {
+			return fmt.Errorf("not an asn mmdb: %s", db.Metadata.DatabaseType)
+		}
+		c.addDB(db, false)
+		return nil
+	}
+}
+
+// 指定 CDN 网段注册表, 默认为内置注册表(Cloudflare、Fastly、CloudFront、Akamai、Gcore、Bunny);
+// 指定了网段文件或额外 CDN 时在其副本上注册, 不修改传入的注册表
+func WithCDNRegistry(r *CDNRegistry) Option {
+	return func(c *Client) error {
+		if r == nil {
+			return fmt.Errorf("cdn registry is nil")
+		}
+		c.cdnBase = r
+		return nil
+	}
+}
+
+// 注册额外的 CDN, 同名时替换内置网段
+func WithCDNProvider(p CDNProvider) Option {
+	return func(c *Client) error {
+		if p.Name == "" || (len(p.Prefixes) == 0 && len(p.ASNs) == 0) {
+			return fmt.Errorf("cdn provider name or ranges is empty")
+		}
+		c.cdnProviders = append(c.cdnProviders, p)
+		return nil
+	}
+}
+
+// 从文件加载 CDN 网段并注册为 name, 同名时替换内置网段; 文件格式见 ParseCDNRanges
+func WithCDNRangeFile(name, path string) Option {
+	return func(c *Client) error {
+		if name == "" || path == "" {
+			return fmt.Errorf("cdn name or path is empty")
+		}
+		c.cdnFiles = append(c.cdnFiles, cdnFile{name: name, path: path})
+		return nil
+	}
+}
+
+// 从文件加载 CDN 网段并追加到同名 CDN, 不存在同名 CDN 时新建
+func WithExtraCDNRangeFile(name, path string) Option {
+	return func(c *Client) error {
+		if name == "" || path == "" {
+			return fmt.Errorf("cdn name or path is empty")
+		}
+		c.cdnFiles = append(c.cdnFiles, cdnFile{name: name, path: path, extra: true})
+		return nil
+	}
+}
+
+// 每隔 interval 检查 CDN 网段文件, 文件变化或内置 Cloudflare 网段更新后重新加载; Close 时停止
+func WithCDNReload(interval time.Duration) Option {
+	return func(c *Client) error {
+		if interval <= 0 {
+			return fmt.Errorf("cdn reload interval must be positive")
 		}
-		c.mmdb = db
-		c.ownMMDB = false
-		c.dbPath = ""
+		c.cdnReload = interval
 		return nil
 	}
 }
@@ -112,7 +297,7 @@ func WithDBReader(db *maxminddb.Reader) Option {
 // 指定当前客户端获取出口 API,默认为内置 API
 func WithIPAPIs(apis ...string) Option {
 	return func(c *Client) error {
-		c.ipAPIs = slices.Clone(apis)
+		c.ipProviders = ipProviders(apis)
 		return nil
 	}
 }
@@ -120,11 +305,187 @@ func WithIPAPIs(apis ...string) Option {
 // 指定当前客户端获取 Geo 信息的API,默认为内置 API
 func WithGeoAPIs(apis ...string) Option {
 	return func(c *Client) error {
-		c.geoAPIs = slices.Clone(apis)
+		c.geoProviders = geoProviders(apis)
+		return nil
+	}
+}
+
+// 指定当前客户端获取出口 IP 的提供者, 可注册自定义实现; 与 WithIPAPIs 互相覆盖
+func WithProviders(providers ...Provider) Option {
+	return func(c *Client) error {
+		if slices.Contains(providers, nil) {
+			return fmt.Errorf("provider is nil")
+		}
+		c.ipProviders = slices.Clone(providers)
 		return nil
 	}
 }
 
+// 指定当前客户端获取 Geo 信息的提供者, 可注册自定义实现; 与 WithGeoAPIs 互相覆盖
+func WithGeoProviders(providers ...Provider) Option {
+	return func(c *Client) error {
+		if slices.Contains(providers, nil) {
+			return fmt.Errorf("provider is nil")
+		}
+		c.geoProviders = slices.Clone(providers)
+		return nil
+	}
+}
+
+// 指定单个 API 请求的超时时间, 默认为 6s
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("attempt timeout must be positive")
+		}
+		c.attemptTimeout = d
+		return nil
+	}
+}
+
+// 指定 GetGeoIPData 的总超时预算, 默认不限制, 仅受调用方 ctx 控制
+func WithTotalTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("total timeout must be positive")
+		}
+		c.totalTimeout = d
+		return nil
+	}
+}
+
+// 启用 ipAPI 竞速模式: 首批并发请求 parallel 个 API, 其余每隔 stagger 追加一个(失败时立即补位),
+// 取第一个有效结果并取消其余请求; 默认顺序尝试, 不能与 WithQuorum 同时使用
+func WithRacing(parallel int, stagger time.Duration) Option {
+	return func(c *Client) error {
+		if parallel <= 0 {
+			return fmt.Errorf("racing parallel must be positive")
+		}
+		if stagger < 0 {
+			return fmt.Errorf("racing stagger must not be negative")
+		}
+		c.raceParallel = parallel
+		c.raceStagger = stagger
+		return nil
+	}
+}
+
+// 指定国家代码接受策略, 默认全部接受
+func WithCountryPolicy(policy CountryPolicy) Option {
+	return func(c *Client) error {
+		if policy == nil {
+			return fmt.Errorf("country policy is nil")
+		}
+		c.countryPolicy = policy
+		return nil
+	}
+}
+
+// 拒绝指定国家代码的结果(不区分大小写), 命中时回退到下一个来源; 例如在境内运行时拒绝 CN 以避免误判
+func WithRejectCountries(codes ...string) Option {
+	rejected := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		rejected[strings.ToUpper(code)] = true
+	}
+	return WithCountryPolicy(func(_, countryCode string) bool {
+		return !rejected[strings.ToUpper(countryCode)]
+	})
+}
+
+// 启用共识模式: 并发查询 k 个 ipAPI(失败时由后续 API 补位), 取多数票的出口 IP,
+// 并在 IPData.ObservedIPs / IPData.Agreement 中返回全部观测结果与得票率; 不能与 WithRacing 同时使用
+func WithQuorum(k int) Option {
+	return func(c *Client) error {
+		if k <= 0 {
+			return fmt.Errorf("quorum must be positive")
+		}
+		c.quorum = k
+		return nil
+	}
+}
+
+// 指定提供者熔断策略: 连续失败 threshold 次后暂停使用 cooldown(此后每次翻倍, 最长 10 分钟);
+// 默认连续失败 3 次熔断 30s
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) error {
+		if threshold <= 0 || cooldown <= 0 {
+			return fmt.Errorf("circuit breaker threshold and cooldown must be positive")
+		}
+		c.failureThreshold = threshold
+		c.cooldown = cooldown
+		return nil
+	}
+}
+
+// 启用双栈检测: 分别请求 ipv4APIs/ipv6APIs, 同时获取 IPv4 与 IPv6 出口并各自定位;
+// 直连时强制 tcp4/tcp6 拨号, 经代理或使用自定义拨号时仅依赖协议族专用的 API 地址;
+// 参数为空时使用 config.IPV4_APIS / config.IPV6_APIS
+func WithDualStack(ipv4APIs, ipv6APIs []string) Option {
+	return func(c *Client) error {
+		c.dualStack = true
+		c.ipv4Providers = ipProviders(ipv4APIs)
+		c.ipv6Providers = ipProviders(ipv6APIs)
+		return nil
+	}
+}
+
+// 在 GetAnalyzed 的 Cloudflare 标签后附加数据中心 IATA 代码, 如 US¹⁺@HKG,
+// 便于发现国家代码与实际服务的数据中心不一致的节点
+func WithColoTag() Option {
+	return func(c *Client) error {
+		c.coloTag = true
+		return nil
+	}
+}
+
+// 指定标签模板, 替换 GetAnalyzed / Analyze 的内置标签格式, 参见 NewTagFormatter
+func WithTagFormatter(f *TagFormatter) Option {
+	return func(c *Client) error {
+		if f == nil {
+			return fmt.Errorf("tag formatter is nil")
+		}
+		c.tagFormatter = f
+		return nil
+	}
+}
+
+// 指定服务可达性探测, Analyze 时与出口检测并发执行, 结果附加到 AnalyzeResult.Probes
+func WithProbes(probes ...Probe) Option {
+	return func(c *Client) error {
+		for _, p := range probes {
+			if p == nil {
+				return fmt.Errorf("probe is nil")
+			}
+		}
+		c.probes = append(c.probes, probes...)
+		return nil
+	}
+}
+
+// ipProviders 将出口 IP 地址列表转换为提供者
+func ipProviders(apis []string) []Provider {
+	if len(apis) == 0 {
+		return nil
+	}
+	out := make([]Provider, 0, len(apis))
+	for _, api := range apis {
+		out = append(out, NewIPProvider(api))
+	}
+	return out
+}
+
+// geoProviders 将 GeoIP 地址列表转换为提供者
+func geoProviders(apis []string) []Provider {
+	if len(apis) == 0 {
+		return nil
+	}
+	out := make([]Provider, 0, len(apis))
+	for _, api := range apis {
+		out = append(out, NewGeoProvider(api))
+	}
+	return out
+}
+
 // 创建新的 ipinfo 客户端
 func New(opts ...Option) (*Client, error) {
 	c := &Client{}
@@ -134,39 +495,96 @@ func New(opts ...Option) (*Client, error) {
 			return nil, err
 		}
 	}
+	if c.quorum > 0 && c.raceParallel > 0 {
+		return nil, fmt.Errorf("racing and quorum are mutually exclusive")
+	}
 
 	// httpClient 默认
 	if c.httpClient == nil {
 		c.httpClient = &http.Client{Timeout: defaultHTTPTimeout}
 	}
 
+	if c.attemptTimeout == 0 {
+		c.attemptTimeout = defaultAttemptTimeout
+	}
+	c.health = newHealthTracker(c.failureThreshold, c.cooldown)
+	if c.tagFormatter == nil {
+		c.tagFormatter = defaultTagFormatter(c.coloTag)
+	}
+
+	// 双栈检测
+	if c.dualStack {
+		if len(c.ipv4Providers) == 0 {
+			c.ipv4Providers = ipProviders(config.IPV4_APIS)
+		}
+		if len(c.ipv6Providers) == 0 {
+			c.ipv6Providers = ipProviders(config.IPV6_APIS)
+		}
+		c.ipv4Client = familyHTTPClient(c.httpClient, "tcp4")
+		c.ipv6Client = familyHTTPClient(c.httpClient, "tcp6")
+	}
+
 	// 初始化 mmdb
-	if c.mmdb == nil {
-		var db *maxminddb.Reader
-		var err error
-		if c.dbPath != "" {
-			db, err = maxminddb.Open(c.dbPath)
-			if err != nil {
-				return nil, fmt.Errorf("open maxmind db: %w", err)
-			}
-		} else {
-			db, err = data.OpenMaxMindDB("")
-			if err != nil {
-				return nil, fmt.Errorf("open default maxmind db: %w", err)
+	for _, path := range c.dbPaths {
+		db, err := OpenReloadableDB(path)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("open maxmind db: %w", err)
+		}
+		if err := c.addReloadableDB(db, true); err != nil {
+			db.Close()
+			c.Close()
+			return nil, fmt.Errorf("add maxmind db: %w", err)
+		}
+	}
+	if !c.hasDB(DBCity, DBCountry) {
+		db, err := OpenReloadableDB("")
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("open default maxmind db: %w", err)
+		}
+		if err := c.addReloadableDB(db, true); err != nil {
+			db.Close()
+			c.Close()
+			return nil, fmt.Errorf("add default maxmind db: %w", err)
+		}
+	}
+
+	// 初始化 ASN 数据库
+	if c.useASN && !c.hasDB(DBASN) {
+		db, err := data.OpenMaxMindASNDB(c.asnPath)
+		switch {
+		case err == nil:
+			c.addDB(db, true)
+			asn := &c.dbs[len(c.dbs)-1]
+			asn.path, asn.defaultPath = c.asnPath, c.asnPath == ""
+			if asn.defaultPath {
+				asn.path = filepath.Join(data.ResolveDataPath(), data.ASNDBFileName)
 			}
+		case c.asnPath == "" && errors.Is(err, data.ErrASNDBUnavailable):
+			slog.Debug("未找到 ASN 数据库，跳过 ASN 查询")
+		default:
+			c.Close()
+			return nil, fmt.Errorf("open asn maxmind db: %w", err)
 		}
-		c.mmdb = db
-		c.ownMMDB = true
 	}
+	c.sortDBs()
 
-	// API 列表兜底
-	if len(c.ipAPIs) == 0 && len(c.geoAPIs) == 0 {
-		c.ipAPIs = slices.Clone(defaultIPAPIs)
-		c.geoAPIs = slices.Clone(defaultGeoAPIs)
+	// 加载 CDN 网段文件, 在注册表副本上注册, 避免影响其他客户端
+	stamps := c.cdnFileStamps()
+	if err := c.ReloadCDNRanges(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if c.cdnReload > 0 && c.hasCDNSources() {
+		c.cdnStop = make(chan struct{})
+		go c.watchCDNRanges(c.cdnReload, stamps, c.cdnStop)
 	}
 
-	if c.mmdb == nil {
-		return nil, fmt.Errorf("mmdb not initialized")
+	// API 列表兜底
+	if len(c.ipProviders) == 0 && len(c.geoProviders) == 0 {
+		c.ipProviders = ipProviders(defaultIPAPIs)
+		c.geoProviders = geoProviders(defaultGeoAPIs)
 	}
 
 	return c, nil
@@ -174,10 +592,28 @@ func New(opts ...Option) (*Client, error) {
 
 // Close 清理资源
 func (c *Client) Close() error {
-	if c == nil || c.mmdb == nil || !c.ownMMDB {
+	if c == nil {
+		return nil
+	}
+	c.closeOnce.Do(func() {
+		if c.cdnStop != nil {
+			close(c.cdnStop)
+		}
+	})
+
+	var errs []error
+	owned := false
+	for _, s := range c.dbs {
+		if s.own {
+			owned = true
+			errs = append(errs, s.close())
+		}
+	}
+	// 保留调用方传入的阅读器, 与关闭前行为一致
+	c.dbs = slices.DeleteFunc(c.dbs, func(s mmdbSource) bool { return s.own })
+	if !owned {
 		return nil
 	}
-	err := c.mmdb.Close()
 
 	if c.httpClient != nil {
 		c.httpClient.CloseIdleConnections()
@@ -187,7 +623,5 @@ func (c *Client) Close() error {
 		tr.CloseIdleConnections()
 	}
 
-	c.mmdb = nil
-	c.ownMMDB = false
-	return err
+	return errors.Join(errs...)
 }
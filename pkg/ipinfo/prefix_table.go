@@ -0,0 +1,135 @@
+package ipinfo
+
+import (
+	"math/bits"
+	"net/netip"
+)
+
+// PrefixTable 基于路径压缩二叉前缀树的最长前缀匹配表, IPv4 与 IPv6 分别建树;
+// 零值可直接使用, 写入与查询不可并发, 构建完成后可并发查询
+type PrefixTable[T any] struct {
+	v4, v6 *prefixNode[T]
+	n      int
+}
+
+// prefixNode 前缀树节点, 仅作分叉用的中间节点 set 为 false
+type prefixNode[T any] struct {
+	prefix netip.Prefix
+	value  T
+	set    bool
+	child  [2]*prefixNode[T]
+}
+
+// Len 已写入的网段数量
+func (t *PrefixTable[T]) Len() int {
+	return t.n
+}
+
+// Insert 写入网段及标签, 网段已存在时替换标签; 4in6 网段(::ffff:a.b.c.d/N)按对应的 IPv4 网段写入
+func (t *PrefixTable[T]) Insert(p netip.Prefix, value T) {
+	if !p.IsValid() {
+		return
+	}
+	p = p.Masked()
+	if p.Addr().Is4In6() {
+		// 掩码后仍为 4in6 地址时前缀长度至少为 96
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	node := &t.v6
+	if p.Addr().Is4() {
+		node = &t.v4
+	}
+
+	for {
+		n := *node
+		if n == nil {
+			*node = &prefixNode[T]{prefix: p, value: value, set: true}
+			t.n++
+			return
+		}
+
+		common := commonBits(n.prefix.Addr(), p.Addr(), min(n.prefix.Bits(), p.Bits()))
+		switch {
+		case common == n.prefix.Bits() && common == p.Bits():
+			// 同一网段
+			if !n.set {
+				t.n++
+			}
+			n.value, n.set = value, true
+			return
+		case common == n.prefix.Bits():
+			// n 包含 p, 继续向下
+			node = &n.child[addrBit(p.Addr(), common)]
+		case common == p.Bits():
+			// p 包含 n, p 成为 n 的父节点
+			leaf := &prefixNode[T]{prefix: p, value: value, set: true}
+			leaf.child[addrBit(n.prefix.Addr(), common)] = n
+			*node = leaf
+			t.n++
+			return
+		default:
+			// 在公共前缀处分叉
+			branch := &prefixNode[T]{prefix: netip.PrefixFrom(p.Addr(), common).Masked()}
+			branch.child[addrBit(n.prefix.Addr(), common)] = n
+			branch.child[addrBit(p.Addr(), common)] = &prefixNode[T]{prefix: p, value: value, set: true}
+			*node = branch
+			t.n++
+			return
+		}
+	}
+}
+
+// Lookup 返回包含 addr 的最长网段及其标签
+func (t *PrefixTable[T]) Lookup(addr netip.Addr) (prefix netip.Prefix, value T, ok bool) {
+	addr = addr.Unmap()
+	n := t.v6
+	if addr.Is4() {
+		n = t.v4
+	}
+
+	var best *prefixNode[T]
+	for n != nil && n.prefix.Contains(addr) {
+		if n.set {
+			best = n
+		}
+		if n.prefix.Bits() == addr.BitLen() {
+			break
+		}
+		n = n.child[addrBit(addr, n.prefix.Bits())]
+	}
+	if best == nil {
+		return prefix, value, false
+	}
+	return best.prefix, best.value, true
+}
+
+// addrBit 返回地址第 i 位(从最高位起)
+func addrBit(a netip.Addr, i int) int {
+	if a.Is4() {
+		b := a.As4()
+		return int(b[i/8]>>(7-i%8)) & 1
+	}
+	b := a.As16()
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits 返回两个同族地址的公共前缀位数, 不超过 limit
+func commonBits(a, b netip.Addr, limit int) int {
+	var x, y []byte
+	if a.Is4() {
+		a4, b4 := a.As4(), b.As4()
+		x, y = a4[:], b4[:]
+	} else {
+		a16, b16 := a.As16(), b.As16()
+		x, y = a16[:], b16[:]
+	}
+	n := 0
+	for i := range x {
+		if d := x[i] ^ y[i]; d != 0 {
+			n += bits.LeadingZeros8(d)
+			break
+		}
+		n += 8
+	}
+	return min(n, limit)
+}
@@ -0,0 +1,85 @@
+package ipinfo
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestPrefixTableLookup(t *testing.T) {
+	var tbl PrefixTable[string]
+	for _, s := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "192.168.1.1/32", "2001:db8::/32", "2001:db8:1::/48"} {
+		tbl.Insert(netip.MustParsePrefix(s), s)
+	}
+	tbl.Insert(netip.MustParsePrefix("10.1.0.0/16"), "replaced")
+	if tbl.Len() != 6 {
+		t.Errorf("网段数量错误: %d", tbl.Len())
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.9.9.9", "10.0.0.0/8"},
+		{"10.1.9.9", "replaced"},
+		{"10.1.2.3", "10.1.2.0/24"},
+		{"::ffff:10.1.2.3", "10.1.2.0/24"},
+		{"192.168.1.1", "192.168.1.1/32"},
+		{"192.168.1.2", ""},
+		{"2001:db8:1::1", "2001:db8:1::/48"},
+		{"2001:db8:2::1", "2001:db8::/32"},
+		{"2001:db9::1", ""},
+	}
+	for _, tt := range tests {
+		_, got, ok := tbl.Lookup(netip.MustParseAddr(tt.ip))
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s 匹配错误: got %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixTable4in6(t *testing.T) {
+	var tbl PrefixTable[string]
+	tbl.Insert(netip.MustParsePrefix("::ffff:198.51.100.0/120"), "mapped")
+
+	// 4in6 网段按 IPv4 网段写入, IPv4 及 4in6 地址均可命中
+	for _, ip := range []string{"198.51.100.7", "::ffff:198.51.100.7"} {
+		prefix, v, ok := tbl.Lookup(netip.MustParseAddr(ip))
+		if !ok || v != "mapped" || prefix.String() != "198.51.100.0/24" {
+			t.Errorf("Lookup(%s) = %v %q %v", ip, prefix, v, ok)
+		}
+	}
+	if _, _, ok := tbl.Lookup(netip.MustParseAddr("198.51.101.1")); ok {
+		t.Error("网段外的地址不应命中")
+	}
+}
+
+func TestPrefixTableMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	randAddr := func() netip.Addr {
+		// 限定在较小空间内以产生大量重叠网段
+		return netip.AddrFrom4([4]byte{10, byte(r.IntN(4)), byte(r.IntN(256)), byte(r.IntN(256))})
+	}
+
+	var tbl PrefixTable[netip.Prefix]
+	var prefixes []netip.Prefix
+	for range 2000 {
+		p := netip.PrefixFrom(randAddr(), 8+r.IntN(25)).Masked()
+		tbl.Insert(p, p)
+		prefixes = append(prefixes, p)
+	}
+
+	for range 5000 {
+		addr := randAddr()
+		var want netip.Prefix
+		for _, p := range prefixes {
+			if p.Contains(addr) && (!want.IsValid() || p.Bits() > want.Bits()) {
+				want = p
+			}
+		}
+		got, label, ok := tbl.Lookup(addr)
+		if ok != want.IsValid() || (ok && (got != want || label != want)) {
+			t.Fatalf("%s 匹配错误: got %s, want %s", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package ipinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProbeTimeout = 5 * time.Second
+	maxProbeBodySize    = 64 * 1024
+)
+
+// Probe 检测当前出口能否正常访问某个服务
+type Probe interface {
+	Name() string
+	Probe(ctx context.Context, hc *http.Client) ProbeResult
+}
+
+// ProbeResult 单个探测的结果
+type ProbeResult struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	StatusCode int    `json:"status,omitempty"`
+	Location   string `json:"location,omitempty"` // 重定向地址
+	LatencyMS  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"` // 请求失败或未通过匹配的原因
+}
+
+// ProbeMatcher 校验探测响应, 不满足时返回原因
+type ProbeMatcher func(resp *http.Response, body []byte) error
+
+// MatchStatus 状态码为 codes 之一
+func MatchStatus(codes ...int) ProbeMatcher {
+	return func(resp *http.Response, _ []byte) error {
+		if !slices.Contains(codes, resp.StatusCode) {
+			return fmt.Errorf("状态码 %d 不在 %v 中", resp.StatusCode, codes)
+		}
+		return nil
+	}
+}
+
+// MatchBodyContains 响应体包含 s
+func MatchBodyContains(s string) ProbeMatcher {
+	return func(_ *http.Response, body []byte) error {
+		if !strings.Contains(string(body), s) {
+			return fmt.Errorf("响应体不包含 %q", s)
+		}
+		return nil
+	}
+}
+
+// MatchBodyNotContains 响应体不包含 s, 常用于识别地区限制页面
+func MatchBodyNotContains(s string) ProbeMatcher {
+	return func(_ *http.Response, body []byte) error {
+		if strings.Contains(string(body), s) {
+			return fmt.Errorf("响应体包含 %q", s)
+		}
+		return nil
+	}
+}
+
+// MatchRedirect 重定向地址包含 s
+func MatchRedirect(s string) ProbeMatcher {
+	return func(resp *http.Response, _ []byte) error {
+		if loc := resp.Header.Get("Location"); !strings.Contains(loc, s) {
+			return fmt.Errorf("重定向地址 %q 不包含 %q", loc, s)
+		}
+		return nil
+	}
+}
+
+// MatchNoRedirect 未重定向到包含 s 的地址
+func MatchNoRedirect(s string) ProbeMatcher {
+	return func(resp *http.Response, _ []byte) error {
+		if loc := resp.Header.Get("Location"); loc != "" && strings.Contains(loc, s) {
+			return fmt.Errorf("重定向到 %q", loc)
+		}
+		return nil
+	}
+}
+
+// HTTPProbe 请求 URL 并依次校验响应, 不跟随重定向; 未指定 Matchers 时要求状态码小于 400
+type HTTPProbe struct {
+	ProbeName string
+	URL       string
+	Method    string            // 默认 GET
+	Header    map[string]string // 额外请求头
+	Timeout   time.Duration     // 默认 5s
+	Matchers  []ProbeMatcher
+}
+
+// Name 探测名称
+func (p *HTTPProbe) Name() string {
+	return p.ProbeName
+}
+
+// Probe 执行探测
+func (p *HTTPProbe) Probe(ctx context.Context, hc *http.Client) ProbeResult {
+	res := ProbeResult{Name: p.ProbeName}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	for key, value := range cfCommonHeaders() {
+		req.Header.Set(key, value)
+	}
+	for key, value := range p.Header {
+		req.Header.Set(key, value)
+	}
+
+	// 不跟随重定向, 以便校验重定向地址
+	noRedirect := *hc
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	start := time.Now()
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		res.LatencyMS = time.Since(start).Milliseconds()
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	res.LatencyMS = time.Since(start).Milliseconds()
+	res.StatusCode = resp.StatusCode
+	res.Location = resp.Header.Get("Location")
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	matchers := p.Matchers
+	if len(matchers) == 0 {
+		matchers = []ProbeMatcher{func(resp *http.Response, _ []byte) error {
+			if resp.StatusCode >= 400 {
+				return fmt.Errorf("状态码 %d", resp.StatusCode)
+			}
+			return nil
+		}}
+	}
+	for _, m := range matchers {
+		if err := m(resp, body); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+	res.OK = true
+	return res
+}
+
+// RunProbes 并发执行探测, 结果顺序与 probes 一致
+func (c *Client) RunProbes(ctx context.Context, probes ...Probe) []ProbeResult {
+	results := make([]ProbeResult, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.Probe(ctx, c.httpClient)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// ProbeSpec 探测的配置定义, 用于从 JSON 加载 HTTPProbe
+type ProbeSpec struct {
+	Name                string            `json:"name"`
+	URL                 string            `json:"url"`
+	Method              string            `json:"method,omitempty"`
+	Header              map[string]string `json:"header,omitempty"`
+	Timeout             string            `json:"timeout,omitempty"` // 如 5s
+	Status              []int             `json:"status,omitempty"`
+	BodyContains        string            `json:"body_contains,omitempty"`
+	BodyNotContains     string            `json:"body_not_contains,omitempty"`
+	RedirectContains    string            `json:"redirect_contains,omitempty"`
+	RedirectNotContains string            `json:"redirect_not_contains,omitempty"`
+}
+
+// HTTPProbe 将配置转换为 HTTPProbe
+func (s ProbeSpec) HTTPProbe() (*HTTPProbe, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("probe name is empty")
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("probe %s: invalid url %q", s.Name, s.URL)
+	}
+
+	p := &HTTPProbe{ProbeName: s.Name, URL: s.URL, Method: s.Method, Header: s.Header}
+	if s.Timeout != "" {
+		if p.Timeout, err = time.ParseDuration(s.Timeout); err != nil {
+			return nil, fmt.Errorf("probe %s: invalid timeout: %w", s.Name, err)
+		}
+	}
+	if len(s.Status) > 0 {
+		p.Matchers = append(p.Matchers, MatchStatus(s.Status...))
+	}
+	if s.BodyContains != "" {
+		p.Matchers = append(p.Matchers, MatchBodyContains(s.BodyContains))
+	}
+	if s.BodyNotContains != "" {
+		p.Matchers = append(p.Matchers, MatchBodyNotContains(s.BodyNotContains))
+	}
+	if s.RedirectContains != "" {
+		p.Matchers = append(p.Matchers, MatchRedirect(s.RedirectContains))
+	}
+	if s.RedirectNotContains != "" {
+		p.Matchers = append(p.Matchers, MatchNoRedirect(s.RedirectNotContains))
+	}
+	return p, nil
+}
+
+// LoadProbes 从 JSON 数组读取探测定义
+func LoadProbes(rd io.Reader) ([]Probe, error) {
+	var specs []ProbeSpec
+	if err := json.NewDecoder(rd).Decode(&specs); err != nil {
+		return nil, fmt.Errorf("decode probes: %w", err)
+	}
+	probes := make([]Probe, 0, len(specs))
+	for _, s := range specs {
+		p, err := s.HTTPProbe()
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+// LoadProbesFile 从 JSON 文件读取探测定义
+func LoadProbesFile(path string) ([]Probe, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("load probes: %w", err)
+	}
+	defer f.Close()
+	return LoadProbes(f)
+}
@@ -0,0 +1,91 @@
+package ipinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunProbes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte("welcome"))
+		case "/blocked":
+			w.Write([]byte(`{"error":"unsupported_country"}`))
+		case "/redirect":
+			http.Redirect(w, r, "/unsupported", http.StatusFound)
+		case "/slow":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	spec := `[
+		{"name": "ok", "url": "` + srv.URL + `/ok", "status": [200], "body_contains": "welcome"},
+		{"name": "blocked", "url": "` + srv.URL + `/blocked", "body_not_contains": "unsupported_country"},
+		{"name": "redirect", "url": "` + srv.URL + `/redirect", "redirect_not_contains": "unsupported"},
+		{"name": "redirect-expected", "url": "` + srv.URL + `/redirect", "status": [302], "redirect_contains": "unsupported"},
+		{"name": "slow", "url": "` + srv.URL + `/slow", "timeout": "50ms"},
+		{"name": "missing", "url": "` + srv.URL + `/missing"}
+	]`
+	probes, err := LoadProbes(strings.NewReader(spec))
+	if err != nil {
+		t.Fatalf("加载探测定义失败: %v", err)
+	}
+
+	cli, err := New()
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	start := time.Now()
+	results := cli.RunProbes(context.Background(), probes...)
+	if time.Since(start) > 900*time.Millisecond {
+		t.Error("探测超时未生效")
+	}
+
+	want := map[string]bool{"ok": true, "blocked": false, "redirect": false, "redirect-expected": true, "slow": false, "missing": false}
+	if len(results) != len(want) {
+		t.Fatalf("结果数量错误: %d", len(results))
+	}
+	for i, r := range results {
+		if r.Name != probes[i].Name() {
+			t.Errorf("结果顺序错误: %s != %s", r.Name, probes[i].Name())
+		}
+		if r.OK != want[r.Name] {
+			t.Errorf("%s: OK = %v, 期望 %v (%s)", r.Name, r.OK, want[r.Name], r.Error)
+		}
+		if !r.OK && r.Error == "" {
+			t.Errorf("%s: 失败时应返回原因", r.Name)
+		}
+	}
+	if results[2].StatusCode != http.StatusFound || results[2].Location != "/unsupported" {
+		t.Errorf("重定向结果错误: %+v", results[2])
+	}
+}
+
+func TestLoadProbesInvalid(t *testing.T) {
+	for _, spec := range []string{
+		`[{"name": "", "url": "https://example.com"}]`,
+		`[{"name": "x", "url": "ftp://example.com"}]`,
+		`[{"name": "x", "url": "https://example.com", "timeout": "soon"}]`,
+		`{"name": "x"}`,
+	} {
+		if _, err := LoadProbes(strings.NewReader(spec)); err == nil {
+			t.Errorf("无效定义应返回错误: %s", spec)
+		}
+	}
+	if _, err := New(WithProbes(nil)); err == nil {
+		t.Error("空探测应返回错误")
+	}
+}
@@ -0,0 +1,262 @@
+package ipinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Family 提供者返回的地址族
+type Family int
+
+const (
+	FamilyAny  Family = iota // 由实际连接决定
+	FamilyIPv4               // 仅返回 IPv4
+	FamilyIPv6               // 仅返回 IPv6
+)
+
+// Capability 提供者能返回的信息
+type Capability uint
+
+const (
+	CapExitIP  Capability = 1 << iota // 出口 IP
+	CapCountry                        // 国家代码
+	CapCity                           // 城市/行政区/经纬度
+	CapASN                            // ASN 及组织
+)
+
+// Has 是否具备指定能力
+func (c Capability) Has(x Capability) bool {
+	return c&x == x
+}
+
+// ParseFunc 解析提供者的响应, 返回其中的 IP 及地理位置信息
+type ParseFunc func(header http.Header, body []byte) (IPData, error)
+
+// Provider 出口 IP / GeoIP 提供者
+type Provider interface {
+	Name() string               // 名称, 用于日志与统计
+	URL() string                // 请求地址
+	Headers() map[string]string // 额外请求头, 覆盖通用请求头
+	Family() Family             // 返回的地址族
+	Capabilities() Capability   // 能返回的信息
+	Parse(header http.Header, body []byte) (IPData, error)
+}
+
+// HTTPProvider 基于 HTTP GET 的提供者实现, 可直接用于注册自定义提供者
+type HTTPProvider struct {
+	Label        string            // 名称, 为空时使用完整请求地址, 以免同一主机的不同地址共用健康统计
+	Endpoint     string            // 请求地址
+	ExtraHeaders map[string]string // 额外请求头
+	AddrFamily   Family            // 返回的地址族
+	Caps         Capability        // 能返回的信息
+	Parser       ParseFunc         // 响应解析函数, 为空时使用启发式解析
+}
+
+func (p *HTTPProvider) Name() string {
+	if p.Label != "" {
+		return p.Label
+	}
+	return p.Endpoint
+}
+
+func (p *HTTPProvider) URL() string                { return p.Endpoint }
+func (p *HTTPProvider) Headers() map[string]string { return p.ExtraHeaders }
+func (p *HTTPProvider) Family() Family             { return p.AddrFamily }
+func (p *HTTPProvider) Capabilities() Capability   { return p.Caps }
+
+func (p *HTTPProvider) Parse(header http.Header, body []byte) (IPData, error) {
+	if p.Parser == nil {
+		return ParseIPText(header, body)
+	}
+	return p.Parser(header, body)
+}
+
+// NewIPProvider 为任意出口 IP 地址创建提供者, 内置地址返回对应的内置实现, 否则使用启发式解析
+func NewIPProvider(url string) Provider {
+	if p, ok := BuiltinProvider(url); ok {
+		return p
+	}
+	return &HTTPProvider{Endpoint: url, Caps: CapExitIP, Parser: ParseIPText}
+}
+
+// NewGeoProvider 为任意 GeoIP 地址创建提供者, 内置地址返回对应的内置实现, 否则按通用 JSON 解析
+func NewGeoProvider(url string) Provider {
+	if p, ok := BuiltinProvider(url); ok {
+		return p
+	}
+	return &HTTPProvider{Endpoint: url, Caps: CapExitIP | CapCountry, Parser: ParseGeoJSON}
+}
+
+// ParseIPText 从任意文本/HTML/JSON 响应中提取出口 IP
+func ParseIPText(header http.Header, bodyBytes []byte) (IPData, error) {
+	// 去除 UTF-8 BOM 并裁剪空白
+	bodyBytes = bytes.TrimPrefix(bodyBytes, []byte("\xef\xbb\xbf"))
+	body := strings.TrimSpace(string(bodyBytes))
+
+	// 如果返回的字符串长度小于 IPv4 或 IPv6 的最大可能长度
+	if (len(body) <= 15 && strings.Contains(body, ".")) || (len(body) <= 39 && strings.Contains(body, ":")) {
+		if info := CreateIPDataFromIP(body); info.IPv4 != "" || info.IPv6 != "" {
+			return *info, nil
+		}
+	}
+
+	// 使用“字符级扫描”从任意文本/HTML 中提取 IP,性能最好
+	ipv4, ipv6 := ExtractIPStrings(body)
+
+	if ipv4 == "" && ipv6 == "" {
+		// 如果没有找到 IP，尝试解析 json 和 正则匹配
+		if strings.Contains(header.Get("Content-Type"), "application/json") {
+			ipv4, ipv6 = getIPFromJSON(bodyBytes)
+		} else {
+			ipv4 = reIPv4.FindString(body)
+			ipv6 = reIPv6.FindString(body)
+		}
+	}
+
+	info := IPData{IPv4: ipv4, IPv6: ipv6}
+	validateIPData(&info)
+	if info.IPv4 == "" && info.IPv6 == "" {
+		return info, fmt.Errorf("未获取到ip, 返回数据: %q", body)
+	}
+	return info, nil
+}
+
+// ParseGeoJSON 按常见字段从 JSON 响应中提取 IP 和国家代码
+func ParseGeoJSON(_ http.Header, body []byte) (IPData, error) {
+	ip, countryCode := ExtractGeoIPStrings(body)
+	info := *CreateIPDataFromIP(ip)
+	info.CountryCode = strings.ToUpper(countryCode)
+	if info.IPv4 == "" && info.IPv6 == "" && info.CountryCode == "" {
+		return info, fmt.Errorf("未获取到ip及国家代码, 返回数据: %q", body)
+	}
+	return info, nil
+}
+
+// jsonKeys 描述 JSON 响应中各字段的候选键, 依次在顶层及 location 嵌套对象中查找
+type jsonKeys struct {
+	IP          []string
+	CountryCode []string
+	CountryName []string
+	Continent   []string
+	City        []string
+	Region      []string
+	RegionCode  []string
+	Postal      []string
+	Latitude    []string
+	Longitude   []string
+	TimeZone    []string
+	ASN         []string // 数值、"AS13335 Cloudflare" 字符串或含 asn/org 的嵌套对象
+	ASOrg       []string
+}
+
+// jsonParser 根据字段候选键生成解析函数
+func jsonParser(keys jsonKeys) ParseFunc {
+	return func(_ http.Header, body []byte) (IPData, error) {
+		var obj map[string]any
+		if err := json.Unmarshal(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), &obj); err != nil {
+			return IPData{}, fmt.Errorf("解析 JSON 失败: %w", err)
+		}
+		scopes := []map[string]any{obj}
+		if loc, ok := obj["location"].(map[string]any); ok {
+			scopes = append(scopes, loc)
+		}
+
+		str := func(ks []string) string {
+			for _, m := range scopes {
+				for _, k := range ks {
+					if s, ok := m[k].(string); ok && s != "" {
+						return s
+					}
+				}
+			}
+			return ""
+		}
+		num := func(ks []string) float64 {
+			for _, m := range scopes {
+				for _, k := range ks {
+					if f, ok := m[k].(float64); ok {
+						return f
+					}
+				}
+			}
+			return 0
+		}
+
+		info := *CreateIPDataFromIP(str(keys.IP))
+		if cc := str(keys.CountryCode); len(cc) == 2 {
+			info.CountryCode = strings.ToUpper(cc)
+		}
+		info.CountryName = str(keys.CountryName)
+		info.ContinentCode = strings.ToUpper(str(keys.Continent))
+		info.City = str(keys.City)
+		info.Region = str(keys.Region)
+		info.RegionCode = strings.ToUpper(str(keys.RegionCode))
+		info.PostalCode = str(keys.Postal)
+		info.Latitude = num(keys.Latitude)
+		info.Longitude = num(keys.Longitude)
+		info.TimeZone = str(keys.TimeZone)
+		info.ASN, info.ASOrganization = jsonASN(scopes, keys.ASN)
+		if org := str(keys.ASOrg); org != "" && info.ASOrganization == "" {
+			info.ASOrganization = org
+		}
+
+		if info.IPv4 == "" && info.IPv6 == "" && info.CountryCode == "" {
+			return info, fmt.Errorf("未获取到ip及国家代码, 返回数据: %q", body)
+		}
+		return info, nil
+	}
+}
+
+// jsonASN 按候选键提取 ASN 及组织, 兼容数值、"AS123 Org" 字符串及嵌套对象
+func jsonASN(scopes []map[string]any, keys []string) (uint, string) {
+	for _, m := range scopes {
+		for _, k := range keys {
+			switch v := m[k].(type) {
+			case float64:
+				if v > 0 {
+					return uint(v), ""
+				}
+			case string:
+				if asn, org := parseASNString(v); asn > 0 {
+					return asn, org
+				}
+			case map[string]any:
+				asn, org := jsonASN([]map[string]any{v}, []string{"asn", "number"})
+				if asn > 0 {
+					if o, ok := v["org"].(string); ok && o != "" {
+						org = o
+					}
+					return asn, org
+				}
+			}
+		}
+	}
+	return 0, ""
+}
+
+// parseASNString 解析 "AS13335 Cloudflare, Inc." 或 "13335" 形式的 ASN
+func parseASNString(s string) (uint, string) {
+	s = strings.TrimSpace(s)
+	num, org, _ := strings.Cut(s, " ")
+	num = strings.TrimPrefix(strings.ToUpper(num), "AS")
+	n, err := strconv.ParseUint(num, 10, 32)
+	if err != nil {
+		return 0, ""
+	}
+	return uint(n), strings.TrimSpace(org)
+}
+
+// validateIPData 清除不合法或地址族不匹配的 IP
+func validateIPData(info *IPData) {
+	if addr, err := netip.ParseAddr(info.IPv4); err != nil || !addr.Unmap().Is4() {
+		info.IPv4 = ""
+	}
+	if addr, err := netip.ParseAddr(info.IPv6); err != nil || !addr.Is6() || addr.Is4In6() {
+		info.IPv6 = ""
+	}
+}
@@ -0,0 +1,192 @@
+package ipinfo
+
+import (
+	"maps"
+	"net/http"
+)
+
+// 常见 GeoIP JSON 字段
+var (
+	// ident.me / tnedi.me
+	identMeKeys = jsonKeys{
+		IP:          []string{"ip"},
+		CountryCode: []string{"cc"},
+		CountryName: []string{"country"},
+		Continent:   []string{"continent"},
+		City:        []string{"city"},
+		Postal:      []string{"postal"},
+		Latitude:    []string{"latitude"},
+		Longitude:   []string{"longitude"},
+		TimeZone:    []string{"tz"},
+		ASN:         []string{"asn"},
+		ASOrg:       []string{"aso"},
+	}
+
+	// ip-api.com
+	ipAPIComKeys = jsonKeys{
+		IP:          []string{"query"},
+		CountryCode: []string{"countryCode"},
+		CountryName: []string{"country"},
+		Continent:   []string{"continentCode"},
+		City:        []string{"city"},
+		Region:      []string{"regionName"},
+		RegionCode:  []string{"region"},
+		Postal:      []string{"zip"},
+		Latitude:    []string{"lat"},
+		Longitude:   []string{"lon"},
+		TimeZone:    []string{"timezone"},
+		ASN:         []string{"as"},
+		ASOrg:       []string{"asname"},
+	}
+
+	// ipapi.co / seeip / ipwhois / ipapi.is 等 snake_case 风格
+	snakeCaseKeys = jsonKeys{
+		IP:          []string{"ip"},
+		CountryCode: []string{"country_code", "countryCode"},
+		CountryName: []string{"country_name", "country"},
+		Continent:   []string{"continent_code"},
+		City:        []string{"city"},
+		Region:      []string{"region", "state"},
+		RegionCode:  []string{"region_code"},
+		Postal:      []string{"postal", "postal_code", "zip"},
+		Latitude:    []string{"latitude", "lat"},
+		Longitude:   []string{"longitude", "lon", "lng"},
+		TimeZone:    []string{"timezone", "time_zone"},
+		ASN:         []string{"asn"},
+		ASOrg:       []string{"org", "organization", "isp"},
+	}
+
+	// myip.wtf
+	myipWTFKeys = jsonKeys{
+		IP:          []string{"YourFuckingIPAddress"},
+		CountryCode: []string{"YourFuckingCountryCode"},
+	}
+
+	// check.torproject.org
+	torProjectKeys = jsonKeys{IP: []string{"IP"}}
+
+	// httpbin.org
+	httpbinKeys = jsonKeys{IP: []string{"origin"}}
+
+	// api.ipify.org
+	ipifyKeys = jsonKeys{IP: []string{"ip"}}
+)
+
+// 部分 API 会拒绝浏览器 UA
+var (
+	postmanHeaders = map[string]string{
+		"User-Agent": "PostmanRuntime/7.32.3",
+		"Accept":     "*/*",
+	}
+	subsCheckHeaders = map[string]string{
+		"User-Agent": "subs-check (https://github.com/beck-8/subs-check)",
+	}
+)
+
+// textIP 纯文本/HTML 出口 IP 提供者
+func textIP(name, url string, family Family) Provider {
+	return &HTTPProvider{Label: name, Endpoint: url, AddrFamily: family, Caps: CapExitIP, Parser: ParseIPText}
+}
+
+// jsonIP 仅返回出口 IP 的 JSON 提供者, 字段变化时退回启发式解析
+func jsonIP(name, url string, family Family, keys jsonKeys) Provider {
+	return &HTTPProvider{Label: name, Endpoint: url, AddrFamily: family, Caps: CapExitIP, Parser: firstOf(jsonParser(keys), ParseIPText)}
+}
+
+// jsonGeo 返回地理位置信息的 JSON 提供者, 字段变化时退回通用 JSON 解析
+func jsonGeo(name, url string, family Family, caps Capability, keys jsonKeys) Provider {
+	return &HTTPProvider{Label: name, Endpoint: url, AddrFamily: family, Caps: CapExitIP | caps, Parser: firstOf(jsonParser(keys), ParseGeoJSON)}
+}
+
+// firstOf 依次尝试多个解析函数, 返回第一个成功的结果
+func firstOf(parsers ...ParseFunc) ParseFunc {
+	return func(header http.Header, body []byte) (info IPData, err error) {
+		for _, parse := range parsers {
+			if info, err = parse(header, body); err == nil {
+				return info, nil
+			}
+		}
+		return info, err
+	}
+}
+
+// newBuiltinProviders 创建内置提供者, 覆盖 config 中的全部 API; 每次返回新的实例, 调用方修改不影响其他客户端
+func newBuiltinProviders() []Provider {
+	return []Provider{
+		// 纯文本出口 IP
+		textIP("aws-checkip", "http://checkip.amazonaws.com", FamilyAny),
+		textIP("aws-global", "https://checkip.global.api.aws", FamilyAny),
+		jsonIP("torproject", "https://check.torproject.org/api/ip", FamilyAny, torProjectKeys),
+		textIP("akamai", "http://whatismyip.akamai.com", FamilyAny),
+		textIP("tnedi-4", "https://4.tnedi.me/ip", FamilyIPv4),
+		textIP("ident-6", "https://6.ident.me/ip", FamilyIPv6),
+		textIP("seeip-4", "https://ipv4.seeip.org/ip", FamilyIPv4),
+		textIP("seeip-6", "https://ipv6.seeip.org/ip", FamilyIPv6),
+		textIP("ip4.me", "https://ip4.me/api/", FamilyIPv4),
+		textIP("ip6.me", "https://ip6.me/api/", FamilyIPv6),
+		textIP("ipinfo.app-4", "https://ipv4.my.ipinfo.app/api/ipDetails.php", FamilyIPv4),
+		textIP("ipinfo.app-6", "https://ipv6.my.ipinfo.app/api/ipDetails.php", FamilyIPv6),
+		textIP("wtfismyip-6", "https://ipv6.wtfismyip.com/text", FamilyIPv6),
+		jsonGeo("myip.wtf", "https://myip.wtf/json", FamilyAny, CapCountry, myipWTFKeys),
+		&HTTPProvider{Label: "checkip.info", Endpoint: "https://checkip.info/ip", ExtraHeaders: maps.Clone(postmanHeaders), Caps: CapExitIP, Parser: ParseIPText},
+		textIP("he.net", "https://checkip.dns.he.net/", FamilyAny),
+		jsonIP("httpbin", "https://httpbin.org/ip", FamilyAny, httpbinKeys),
+		textIP("dyndns", "http://checkip.dyndns.com/", FamilyAny),
+		textIP("vore", "https://api.vore.top/api/IPdata", FamilyAny),
+		textIP("ipapi.is-ip", "https://api.ipapi.is/ip", FamilyAny),
+		textIP("ifconfig.me", "http://ifconfig.me/ip", FamilyAny),
+		textIP("ipinfo.io", "https://ipinfo.io/ip", FamilyAny),
+		textIP("afraid", "https://freedns.afraid.org/dynamic/check.php", FamilyAny),
+		textIP("ipw-test", "https://test.ipw.cn/", FamilyAny),
+		textIP("ipw-6", "https://6.ipw.cn/", FamilyIPv6),
+		jsonIP("ipify-6", "https://api6.ipify.org?format=json", FamilyIPv6, ipifyKeys),
+
+		// 国内大厂接口
+		textIP("baidu", "https://qifu-api.baidubce.com/ip/local/geo/v1/district", FamilyAny),
+		textIP("qq", "https://r.inews.qq.com/api/ip2city", FamilyAny),
+		textIP("letv", "https://g3.letv.com/r?format=1", FamilyAny),
+		textIP("ctrip", "https://cdid.c-ctrip.com/model-poc2/h", FamilyAny),
+		textIP("pconline", "https://whois.pconline.com.cn/ipJson.jsp", FamilyAny),
+		textIP("bilibili", "https://api.live.bilibili.com/xlive/web-room/v1/index/getIpInfo", FamilyAny),
+
+		// GeoIP
+		jsonGeo("ident-4", "https://4.ident.me/json", FamilyIPv4, CapCountry|CapCity|CapASN, identMeKeys),
+		jsonGeo("tnedi-4-geo", "https://4.tnedi.me/json", FamilyIPv4, CapCountry|CapCity|CapASN, identMeKeys),
+		jsonGeo("ident", "https://ident.me/json", FamilyAny, CapCountry|CapCity|CapASN, identMeKeys),
+		jsonGeo("tnedi", "https://tnedi.me/json", FamilyAny, CapCountry|CapCity|CapASN, identMeKeys),
+		jsonGeo("ident-a", "https://a.ident.me/json", FamilyAny, CapCountry|CapCity|CapASN, identMeKeys),
+		jsonGeo("seeip", "https://api.seeip.org/geoip", FamilyAny, CapCountry|CapCity|CapASN, snakeCaseKeys),
+		jsonGeo("ipapi.is", "https://api.ipapi.is", FamilyAny, CapCountry|CapCity|CapASN, snakeCaseKeys),
+		&HTTPProvider{Label: "checkip.info-geo", Endpoint: "https://checkip.info/json", ExtraHeaders: maps.Clone(postmanHeaders), Caps: CapExitIP | CapCountry, Parser: firstOf(ParseGeoJSON, ParseIPText)},
+		&HTTPProvider{Label: "ip-api.io", Endpoint: "https://ip-api.io/json", Caps: CapExitIP | CapCountry, Parser: firstOf(ParseGeoJSON, ParseIPText)},
+		&HTTPProvider{Label: "ip-api.io-v1", Endpoint: "https://ip-api.io/api/v1/ip", Caps: CapExitIP | CapCountry, Parser: firstOf(ParseGeoJSON, ParseIPText)},
+		jsonGeo("ip-api.com", "http://ip-api.com/json", FamilyAny, CapCountry|CapCity|CapASN, ipAPIComKeys),
+		jsonGeo("ipwhois", "https://ipwhois.app/json/", FamilyAny, CapCountry|CapCity|CapASN, snakeCaseKeys),
+		jsonGeo("ipapi.co", "https://ipapi.co/json", FamilyAny, CapCountry|CapCity|CapASN, snakeCaseKeys),
+		&HTTPProvider{Label: "122911", Endpoint: "https://ip.122911.xyz/api/ipinfo", ExtraHeaders: maps.Clone(subsCheckHeaders), Caps: CapExitIP | CapCountry, Parser: firstOf(ParseGeoJSON, ParseIPText)},
+	}
+}
+
+// builtinIndex 按地址索引内置提供者的位置
+var builtinIndex = func() map[string]int {
+	providers := newBuiltinProviders()
+	m := make(map[string]int, len(providers))
+	for i, p := range providers {
+		m[p.URL()] = i
+	}
+	return m
+}()
+
+// BuiltinProvider 返回指定地址对应的内置提供者, 每次返回新的实例
+func BuiltinProvider(url string) (Provider, bool) {
+	i, ok := builtinIndex[url]
+	if !ok {
+		return nil, false
+	}
+	return newBuiltinProviders()[i], true
+}
+
+// BuiltinProviders 返回全部内置提供者, 每次返回新的实例
+func BuiltinProviders() []Provider {
+	return newBuiltinProviders()
+}
@@ -0,0 +1,168 @@
+package ipinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/sinspired/checkip/internal/config"
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestBuiltinProvidersCoverConfig(t *testing.T) {
+	lists := [][]string{config.IP_APIS, config.GEOIP_APIS, config.IPV4_APIS, config.IPV6_APIS, defaultIPAPIs, defaultGeoAPIs}
+	for _, list := range lists {
+		for _, url := range list {
+			if _, ok := BuiltinProvider(url); !ok {
+				t.Errorf("%s 缺少内置提供者", url)
+			}
+		}
+	}
+
+	// 名称唯一, 便于统计
+	var names []string
+	for _, p := range BuiltinProviders() {
+		if slices.Contains(names, p.Name()) {
+			t.Errorf("内置提供者名称重复: %s", p.Name())
+		}
+		names = append(names, p.Name())
+	}
+}
+
+func TestBuiltinProvidersCopy(t *testing.T) {
+	// 修改返回的提供者不影响其他调用方
+	for _, p := range BuiltinProviders() {
+		hp := p.(*HTTPProvider)
+		hp.Endpoint = "http://example.invalid"
+		if hp.ExtraHeaders != nil {
+			hp.ExtraHeaders["User-Agent"] = "modified"
+		}
+	}
+	for _, p := range BuiltinProviders() {
+		if p.URL() == "http://example.invalid" || p.Headers()["User-Agent"] == "modified" {
+			t.Fatalf("内置提供者被修改: %s", p.Name())
+		}
+	}
+	p, _ := BuiltinProvider("https://checkip.info/ip")
+	p.(*HTTPProvider).ExtraHeaders["User-Agent"] = "modified"
+	if p, _ := BuiltinProvider("https://checkip.info/ip"); p.Headers()["User-Agent"] == "modified" {
+		t.Error("内置提供者请求头被修改")
+	}
+}
+
+func TestHTTPProviderName(t *testing.T) {
+	// 同一主机的不同地址名称不同, 健康统计互不影响
+	a := NewIPProvider("https://ip.example.com/v4")
+	b := NewIPProvider("https://ip.example.com/v6")
+	if a.Name() == b.Name() {
+		t.Errorf("同一主机的不同地址名称相同: %s", a.Name())
+	}
+	if p := (&HTTPProvider{Label: "example", Endpoint: "https://ip.example.com/v4"}); p.Name() != "example" {
+		t.Errorf("应优先使用 Label: %s", p.Name())
+	}
+}
+
+func TestProviderParse(t *testing.T) {
+	jsonHeader := http.Header{"Content-Type": []string{"application/json"}}
+	tests := []struct {
+		url     string
+		body    string
+		ip      string
+		country string
+		city    string
+	}{
+		{"http://checkip.amazonaws.com", "45.65.122.98\n", "45.65.122.98", "", ""},
+		{"https://check.torproject.org/api/ip", `{"IsTor":false,"IP":"45.65.122.98"}`, "45.65.122.98", "", ""},
+		{"https://httpbin.org/ip", `{"origin": "45.65.122.98"}`, "45.65.122.98", "", ""},
+		{"http://checkip.dyndns.com/", `<html><body>Current IP Address: 45.65.122.98</body></html>`, "45.65.122.98", "", ""},
+		{"https://myip.wtf/json", `{"YourFuckingIPAddress":"45.65.122.98","YourFuckingCountryCode":"US"}`, "45.65.122.98", "US", ""},
+		{"https://ident.me/json", `{"ip":"45.65.122.98","cc":"US","country":"United States","city":"Los Angeles","tz":"America/Los_Angeles"}`, "45.65.122.98", "US", "Los Angeles"},
+		{"http://ip-api.com/json", `{"status":"success","country":"United States","countryCode":"US","region":"CA","regionName":"California","city":"Los Angeles","lat":34.05,"lon":-118.24,"query":"45.65.122.98"}`, "45.65.122.98", "US", "Los Angeles"},
+		{"https://api.ipapi.is", `{"ip":"45.65.122.98","location":{"country_code":"US","city":"Los Angeles"}}`, "45.65.122.98", "US", "Los Angeles"},
+		{"https://example.com/unknown-geo", `{"ip":"2a09:bac1:31e0:8::245:d4","country_code":"jp"}`, "2a09:bac1:31e0:8::245:d4", "JP", ""},
+	}
+	for _, tt := range tests {
+		p := NewGeoProvider(tt.url)
+		info, err := p.Parse(jsonHeader, []byte(tt.body))
+		if err != nil {
+			t.Errorf("%s 解析失败: %v", p.Name(), err)
+			continue
+		}
+		ip := info.IPv4
+		if ip == "" {
+			ip = info.IPv6
+		}
+		if ip != tt.ip || info.CountryCode != tt.country || info.City != tt.city {
+			t.Errorf("%s 解析结果错误: %+v", p.Name(), info)
+		}
+	}
+}
+
+func TestProviderParseASN(t *testing.T) {
+	tests := []struct {
+		url  string
+		body string
+		asn  uint
+		org  string
+	}{
+		{"https://ident.me/json", `{"ip":"45.65.122.98","cc":"US","asn":13335,"aso":"CLOUDFLARENET"}`, 13335, "CLOUDFLARENET"},
+		{"http://ip-api.com/json", `{"countryCode":"US","query":"45.65.122.98","as":"AS13335 Cloudflare, Inc.","asname":"CLOUDFLARENET"}`, 13335, "Cloudflare, Inc."},
+		{"https://ipapi.co/json", `{"ip":"45.65.122.98","country_code":"US","asn":"AS13335","org":"CLOUDFLARENET"}`, 13335, "CLOUDFLARENET"},
+		{"https://api.ipapi.is", `{"ip":"45.65.122.98","asn":{"asn":13335,"org":"Cloudflare, Inc."},"location":{"country_code":"US"}}`, 13335, "Cloudflare, Inc."},
+	}
+	for _, tt := range tests {
+		p := NewGeoProvider(tt.url)
+		info, err := p.Parse(nil, []byte(tt.body))
+		if err != nil {
+			t.Errorf("%s 解析失败: %v", p.Name(), err)
+			continue
+		}
+		if info.ASN != tt.asn || info.ASOrganization != tt.org {
+			t.Errorf("%s ASN 解析错误: got AS%d %q, want AS%d %q", p.Name(), info.ASN, info.ASOrganization, tt.asn, tt.org)
+		}
+	}
+}
+
+func TestWithProviders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("exit=45.65.122.98;cc=US"))
+	}))
+	defer srv.Close()
+
+	custom := &HTTPProvider{
+		Label:        "custom",
+		Endpoint:     srv.URL,
+		ExtraHeaders: map[string]string{"X-Token": "secret"},
+		Caps:         CapExitIP,
+	}
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithDBReader(db), WithProviders(custom))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	info, err := cli.GetGeoIPData(context.Background())
+	if err != nil {
+		t.Fatalf("自定义提供者获取失败: %v", err)
+	}
+	if info.IPv4 != "45.65.122.98" || info.CountryCode == "" {
+		t.Errorf("自定义提供者结果错误: %+v", info)
+	}
+
+	if _, err := New(WithDBReader(db), WithProviders(nil)); err == nil {
+		t.Error("nil 提供者应返回错误")
+	}
+}
@@ -0,0 +1,90 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// quorumExitIP 并发查询 quorum 个 ipAPI, 失败时由后续 API 补位, 收集到 quorum 个有效应答或 API 用尽后投票;
+// 以 IPv4 优先的出口地址作为投票依据, 票数相同时取先到者
+func (c *Client) quorumExitIP(ctx context.Context, providers []Provider) (IPData, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		p    Provider
+		info IPData
+		err  error
+	}
+
+	resultChan := make(chan result, len(providers))
+	next, pending := 0, 0
+	launch := func() {
+		p := providers[next]
+		next++
+		pending++
+		go func() {
+			info, err := c.fetchExitIP(ctx, c.httpClient, p)
+			resultChan <- result{p, info, err}
+		}()
+	}
+
+	for next < len(providers) && next < c.quorum {
+		launch()
+	}
+
+	votes := make(map[string]int)
+	samples := make(map[string]IPData)
+	var order []string
+	answered := 0
+
+collect:
+	for pending > 0 && answered < c.quorum {
+		select {
+		case r := <-resultChan:
+			pending--
+			ip := r.info.IPv4
+			if ip == "" {
+				ip = r.info.IPv6
+			}
+			if r.err != nil || ip == "" {
+				slog.Debug(fmt.Sprintf("从 ipAPI 获取出口 IP 失败: %s, err: %v", r.p.Name(), r.err))
+				if next < len(providers) {
+					launch()
+				}
+				continue
+			}
+			slog.Debug(fmt.Sprintf("%s : %s (共识)", r.p.Name(), ip))
+			answered++
+			if votes[ip] == 0 {
+				order = append(order, ip)
+				samples[ip] = r.info
+			}
+			votes[ip]++
+		case <-ctx.Done():
+			slog.Debug(fmt.Sprintf("收到停止信号，停止共识查询: %v", ctx.Err()))
+			break collect
+		}
+	}
+
+	if answered == 0 {
+		if ctx.Err() != nil {
+			return IPData{}, ctx.Err()
+		}
+		return IPData{}, errors.New("所有 ipAPI 均未能获取到有效的IP地址")
+	}
+
+	// 按票数降序, 票数相同保持先到顺序
+	slices.SortStableFunc(order, func(a, b string) int { return votes[b] - votes[a] })
+
+	info := samples[order[0]]
+	info.ObservedIPs = order
+	info.Agreement = float64(votes[order[0]]) / float64(answered)
+	if len(order) > 1 {
+		slog.Debug(fmt.Sprintf("共识模式观测到多个出口 IP: %v, 得票率 %.2f", order, info.Agreement))
+	}
+	return info, nil
+}
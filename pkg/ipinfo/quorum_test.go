@@ -0,0 +1,71 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestQuorumExitIP(t *testing.T) {
+	newIPServer := func(ip string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, ip)
+		}))
+	}
+	a1 := newIPServer("45.65.122.98")
+	defer a1.Close()
+	a2 := newIPServer("45.65.122.98")
+	defer a2.Close()
+	b := newIPServer("45.65.122.99")
+	defer b.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(
+		WithDBReader(db),
+		WithIPAPIs(bad.URL, a1.URL, b.URL, a2.URL),
+		WithQuorum(3),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := cli.GetGeoIPData(ctx)
+	if err != nil {
+		t.Fatalf("共识模式获取出口 IP 失败: %v", err)
+	}
+	t.Logf("IP: %s, Observed: %v, Agreement: %.2f", info.IPv4, info.ObservedIPs, info.Agreement)
+	if info.IPv4 != "45.65.122.98" {
+		t.Errorf("多数票 IP 错误: %s", info.IPv4)
+	}
+	if !slices.Equal(info.ObservedIPs, []string{"45.65.122.98", "45.65.122.99"}) {
+		t.Errorf("观测到的 IP 列表错误: %v", info.ObservedIPs)
+	}
+	if info.Agreement < 0.66 || info.Agreement > 0.67 {
+		t.Errorf("得票率错误: %v", info.Agreement)
+	}
+}
+
+func TestQuorumWithRacing(t *testing.T) {
+	// 共识与竞速模式互斥, 同时指定时应返回错误而非静默忽略其一
+	if _, err := New(WithQuorum(3), WithRacing(2, time.Second)); err == nil {
+		t.Error("同时启用共识与竞速模式应返回错误")
+	}
+}
@@ -0,0 +1,72 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// raceExitIP 竞速获取出口 IP: 首批并发 raceParallel 个 API, 其余按 raceStagger 错峰追加,
+// 某个请求失败时立即补位; 取第一个有效结果并取消其余请求
+func (c *Client) raceExitIP(ctx context.Context, providers []Provider) (IPData, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		p    Provider
+		info IPData
+		err  error
+	}
+
+	// 缓冲区足够容纳所有结果，提前返回时不会阻塞 goroutine
+	resultChan := make(chan result, len(providers))
+	next, pending := 0, 0
+	launch := func() {
+		p := providers[next]
+		next++
+		pending++
+		go func() {
+			info, err := c.fetchExitIP(ctx, c.httpClient, p)
+			resultChan <- result{p, info, err}
+		}()
+	}
+
+	for next < len(providers) && next < c.raceParallel {
+		launch()
+	}
+
+	var stagger <-chan time.Time
+	var timer *time.Timer
+	if next < len(providers) {
+		timer = time.NewTimer(c.raceStagger)
+		defer timer.Stop()
+		stagger = timer.C
+	}
+
+	for pending > 0 {
+		select {
+		case r := <-resultChan:
+			pending--
+			if r.err == nil && (r.info.IPv4 != "" || r.info.IPv6 != "") {
+				slog.Debug(fmt.Sprintf("%s : IPv4=%s IPv6=%s (竞速)", r.p.Name(), r.info.IPv4, r.info.IPv6))
+				return r.info, nil
+			}
+			slog.Debug(fmt.Sprintf("从 ipAPI 获取出口 IP 失败: %s, err: %v", r.p.Name(), r.err))
+			// 失败立即补位，不等待错峰间隔
+			if next < len(providers) {
+				launch()
+			}
+		case <-stagger:
+			if next < len(providers) {
+				launch()
+				timer.Reset(c.raceStagger)
+			}
+		case <-ctx.Done():
+			slog.Debug(fmt.Sprintf("收到停止信号，停止竞速 ipAPI: %v", ctx.Err()))
+			return IPData{}, ctx.Err()
+		}
+	}
+	return IPData{}, errors.New("所有 ipAPI 均未能获取到有效的IP地址")
+}
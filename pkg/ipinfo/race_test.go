@@ -0,0 +1,74 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestRaceExitIP(t *testing.T) {
+	// 慢速 API：模拟黑洞
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	// 失败 API
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer bad.Close()
+
+	// 正常 API
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "45.65.122.98")
+	}))
+	defer fast.Close()
+
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(
+		WithDBReader(db),
+		WithIPAPIs(slow.URL),
+		WithRacing(2, time.Second),
+	)
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	// 慢速 API 排在最前，失败 API 应立即由正常 API 补位，而不是等待错峰间隔
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	info, err := cli.raceExitIP(ctx, ipProviders([]string{slow.URL, bad.URL, fast.URL, slow.URL}))
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("竞速获取出口 IP 失败: %v", err)
+	}
+	if info.IPv4 != "45.65.122.98" {
+		t.Errorf("竞速结果错误: %+v", info)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("竞速未能及时返回, 耗时 %v", elapsed)
+	}
+
+	// 全部失败时返回错误
+	info, err = cli.raceExitIP(ctx, ipProviders([]string{bad.URL, bad.URL}))
+	if err == nil {
+		t.Errorf("全部失败时应返回错误, got: %+v", info)
+	}
+}
@@ -0,0 +1,137 @@
+package ipinfo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+	"github.com/sinspired/checkip/internal/data"
+)
+
+// ErrDBClosed 数据库已关闭
+var ErrDBClosed = errors.New("maxmind db closed")
+
+// refReader 带引用计数的阅读器, 计数归零时关闭
+type refReader struct {
+	reader *maxminddb.Reader
+	refs   atomic.Int64 // 当前持有者(ReloadableDB) + 进行中的查询
+}
+
+// release 释放一次引用, 最后一次释放时关闭阅读器
+func (r *refReader) release() error {
+	if r.refs.Add(-1) == 0 {
+		return r.reader.Close()
+	}
+	return nil
+}
+
+// ReloadableDB 可热替换的 MaxMind 数据库; 替换后进行中的查询继续使用旧阅读器, 结束后旧阅读器才关闭
+type ReloadableDB struct {
+	path        string
+	defaultPath bool // 未指定路径, 使用数据目录中由内置数据解压的数据库
+	open        func(string) (*maxminddb.Reader, error)
+
+	mu     sync.Mutex // 串行化 Reload / Close
+	closed bool
+	cur    atomic.Pointer[refReader]
+}
+
+// OpenReloadableDB 打开可热替换的 MaxMind 数据库; path 为空时使用数据目录中的 GeoLite2-City.mmdb,
+// 不存在时从内置数据解压
+func OpenReloadableDB(path string) (*ReloadableDB, error) {
+	db, err := data.OpenMaxMindDB(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &ReloadableDB{path: path, open: data.OpenMaxMindDB}
+	if path == "" {
+		d.path = filepath.Join(data.ResolveDataPath(), data.CityDBFileName)
+		d.defaultPath = true
+	}
+	d.swap(db)
+	return d, nil
+}
+
+// Path 数据库文件路径
+func (d *ReloadableDB) Path() string {
+	return d.path
+}
+
+// Metadata 当前数据库的元数据, 已关闭时返回 ErrDBClosed
+func (d *ReloadableDB) Metadata() (maxminddb.Metadata, error) {
+	r := d.acquire()
+	if r == nil {
+		return maxminddb.Metadata{}, ErrDBClosed
+	}
+	defer r.release()
+	return r.reader.Metadata, nil
+}
+
+// Reload 重新打开数据库文件并原子替换, 失败或数据库类型变化时保留原数据库
+func (d *ReloadableDB) Reload() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrDBClosed
+	}
+
+	db, err := d.open(d.path)
+	if err != nil {
+		return fmt.Errorf("reload maxmind db: %w", err)
+	}
+	if cur := d.cur.Load(); cur != nil && DetectDBType(cur.reader) != DetectDBType(db) {
+		db.Close()
+		return fmt.Errorf("reload maxmind db: %s 的数据库类型由 %s 变为 %s", d.path, DetectDBType(cur.reader), DetectDBType(db))
+	}
+	if err := d.swap(db); err != nil {
+		slog.Debug(fmt.Sprintf("关闭旧 MaxMind 数据库失败: %v", err))
+	}
+	slog.Debug(fmt.Sprintf("已重新加载 MaxMind 数据库: %s", d.path))
+	return nil
+}
+
+// Close 关闭数据库, 进行中的查询结束后释放阅读器
+func (d *ReloadableDB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	if old := d.cur.Swap(nil); old != nil {
+		return old.release()
+	}
+	return nil
+}
+
+// swap 替换当前阅读器并释放旧阅读器的持有引用
+func (d *ReloadableDB) swap(db *maxminddb.Reader) error {
+	r := &refReader{reader: db}
+	r.refs.Store(1)
+	if old := d.cur.Swap(r); old != nil {
+		return old.release()
+	}
+	return nil
+}
+
+// acquire 获取当前阅读器并增加引用, 用完后调用 release; 已关闭时返回 nil
+func (d *ReloadableDB) acquire() *refReader {
+	for {
+		r := d.cur.Load()
+		if r == nil {
+			return nil
+		}
+		n := r.refs.Load()
+		if n <= 0 {
+			// 已被替换且正在关闭, 重新读取当前阅读器
+			continue
+		}
+		if r.refs.CompareAndSwap(n, n+1) {
+			return r
+		}
+	}
+}
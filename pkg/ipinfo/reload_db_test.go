@@ -0,0 +1,126 @@
+package ipinfo
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+// copyCityDB 将内置数据库复制到临时目录
+func copyCityDB(t *testing.T) string {
+	t.Helper()
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	db.Close()
+
+	b, err := os.ReadFile(filepath.Join(data.ResolveDataPath(), data.CityDBFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), data.CityDBFileName)
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReloadableDB(t *testing.T) {
+	path := copyCityDB(t)
+	db, err := OpenReloadableDB(path)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+
+	// 进行中的查询持有旧阅读器
+	old := db.acquire()
+	if err := db.Reload(); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if cur := db.acquire(); cur == old {
+		t.Error("重新加载后应使用新阅读器")
+	} else {
+		cur.release()
+	}
+	var rec mmdbRecord
+	if err := old.reader.Lookup(netip.MustParseAddr("8.8.8.8")).Decode(&rec); err != nil {
+		t.Errorf("替换后进行中的查询应可继续: %v", err)
+	}
+	old.release()
+	if err := old.reader.Lookup(netip.MustParseAddr("8.8.8.8")).Decode(&rec); err == nil {
+		t.Error("旧阅读器释放后应关闭")
+	}
+
+	// 文件损坏时保留原数据库
+	if err := os.WriteFile(path, []byte("<html>not found</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reload(); err == nil {
+		t.Error("无效文件应返回错误")
+	}
+	if _, err := db.Metadata(); err != nil {
+		t.Errorf("重新加载失败后应保留原数据库: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Errorf("关闭失败: %v", err)
+	}
+	if _, err := db.Metadata(); err != ErrDBClosed {
+		t.Errorf("关闭后应返回 ErrDBClosed: %v", err)
+	}
+	if err := db.Reload(); err != ErrDBClosed {
+		t.Errorf("关闭后重新加载应返回 ErrDBClosed: %v", err)
+	}
+}
+
+func TestClientReloadDatabases(t *testing.T) {
+	path := copyCityDB(t)
+	db, err := OpenReloadableDB(path)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithReloadableDB(db))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+	if dbs := cli.Databases(); len(dbs) != 1 || dbs[0] != DBCity {
+		t.Fatalf("数据库列表错误: %v", dbs)
+	}
+
+	// 查询与重新加载并发进行
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				info := &IPData{IPv4: "8.8.8.8"}
+				if _, err := cli.LookupGeoIPDataWithMMDB(info); err != nil || info.CountryCode == "" {
+					t.Errorf("重新加载期间查询失败: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for range 20 {
+		if err := cli.ReloadDatabases(); err != nil {
+			t.Errorf("重新加载失败: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,109 @@
+package ipinfo
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// DefaultTagTemplate 默认标签模板, 与 GetAnalyzed 文档中的格式一致;
+// 启用 WithColoTag 时 "colo" 子模板输出 @IATA 后缀, 否则为空
+const DefaultTagTemplate = `
+{{- if eq .Category.String "local_isp"}}Local ISP
+{{- else if eq .Category.String "no_cf"}}{{.Loc}}²
+{{- else if eq .Category.String "bad_cf_node"}}{{.Loc}}⁻¹
+{{- else if eq .Category.String "warp"}}{{.Loc}}³
+	{{- if eq .IPData.WARP "warp+"}}⁺{{else if eq .IPData.WARP "zero_trust"}}ᶻ{{end}}{{block "colo" .}}{{end}}
+{{- else if eq .Category.String "cf_same_country"}}{{.Loc}}¹⁺{{template "colo" .}}
+{{- else}}{{.Loc}}¹-{{or .CFLoc "🏴‍☠️"}}⁰{{template "colo" .}}
+{{- end}}`
+
+// coloTagTemplate WithColoTag 使用的 "colo" 子模板
+const coloTagTemplate = `{{define "colo"}}{{with .Colo.Code}}@{{.}}{{end}}{{end}}`
+
+// TagFormatter 根据分析结果生成节点标签
+type TagFormatter struct {
+	tmpl *template.Template
+}
+
+// NewTagFormatter 解析 text/template 格式的标签模板, 模板数据为 *AnalyzeResult;
+// 可用函数: flag(国旗 emoji)、sup(上标)、country(国家英文名)、asn(AS 编号, 如 AS13335)
+func NewTagFormatter(text string) (*TagFormatter, error) {
+	tmpl, err := template.New("tag").Funcs(tagFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse tag template: %w", err)
+	}
+	return &TagFormatter{tmpl: tmpl}, nil
+}
+
+// Format 生成标签, 去除首尾空白
+func (f *TagFormatter) Format(r *AnalyzeResult) (string, error) {
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, r); err != nil {
+		return "", fmt.Errorf("execute tag template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// defaultTagFormatter 内置标签模板, colo 为 true 时附加数据中心代码
+func defaultTagFormatter(colo bool) *TagFormatter {
+	text := DefaultTagTemplate
+	if colo {
+		text += coloTagTemplate
+	}
+	f, err := NewTagFormatter(text)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+var tagFuncs = template.FuncMap{
+	"flag":    flagEmoji,
+	"sup":     superscript,
+	"country": countryName,
+	"asn":     asnString,
+}
+
+// flagEmoji 将两位国家代码转换为国旗 emoji, 无效代码返回空
+func flagEmoji(code string) string {
+	code = strings.ToUpper(code)
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return ""
+	}
+	return string([]rune{rune(code[0]) - 'A' + 0x1F1E6, rune(code[1]) - 'A' + 0x1F1E6})
+}
+
+var superscripts = strings.NewReplacer(
+	"0", "⁰", "1", "¹", "2", "²", "3", "³", "4", "⁴",
+	"5", "⁵", "6", "⁶", "7", "⁷", "8", "⁸", "9", "⁹",
+	"+", "⁺", "-", "⁻", "=", "⁼", "(", "⁽", ")", "⁾",
+)
+
+// superscript 将数字及 +-=() 转换为上标, 其余字符保持不变
+func superscript(v any) string {
+	return superscripts.Replace(fmt.Sprint(v))
+}
+
+// countryName 返回国家代码对应的英文名称, 无法识别时返回原代码
+func countryName(code string) string {
+	region, err := language.ParseRegion(code)
+	if err != nil || !region.IsCountry() {
+		return code
+	}
+	if name := display.English.Regions().Name(region); name != "" {
+		return name
+	}
+	return code
+}
+
+// asnString 格式化 AS 编号, 0 返回空
+func asnString(asn uint) string {
+	if asn == 0 {
+		return ""
+	}
+	return fmt.Sprintf("AS%d", asn)
+}
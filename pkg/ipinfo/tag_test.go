@@ -0,0 +1,64 @@
+package ipinfo
+
+import "testing"
+
+func TestDefaultTagTemplate(t *testing.T) {
+	f := defaultTagFormatter(false)
+	tests := []struct {
+		r    AnalyzeResult
+		want string
+	}{
+		{AnalyzeResult{Loc: "AS", Category: CategoryLocalISP}, "Local ISP"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryNoCF}, "HK²"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryBadCFNode}, "HK⁻¹"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryCFSameCountry, Trace: CFTrace{Loc: "HK"}}, "HK¹⁺"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryCFDifferentCountry, Trace: CFTrace{Loc: "US"}}, "HK¹-US⁰"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryCFDifferentCountry}, "HK¹-🏴‍☠️⁰"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryWARP, IPData: IPData{WARP: WARPOn}}, "HK³"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryWARP, IPData: IPData{WARP: WARPPlus}}, "HK³⁺"},
+		{AnalyzeResult{Loc: "HK", Category: CategoryWARP, IPData: IPData{WARP: WARPZeroTrust}}, "HK³ᶻ"},
+	}
+	for _, tt := range tests {
+		got, err := f.Format(&tt.r)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: 标签 %q (%v), 期望 %q", tt.r.Category, got, err, tt.want)
+		}
+	}
+}
+
+func TestTagFormatter(t *testing.T) {
+	f, err := NewTagFormatter(`{{flag .Loc}} {{country .Loc}} {{.Loc}}{{sup "1+"}} {{asn .IPData.ASN}} {{.Colo.City}}`)
+	if err != nil {
+		t.Fatalf("解析模板失败: %v", err)
+	}
+	r := &AnalyzeResult{
+		Loc:      "HK",
+		Category: CategoryCFSameCountry,
+		IPData:   IPData{ASN: 13335},
+		Colo:     Colo{Code: "HKG", City: "Hong Kong"},
+	}
+	if got, _ := f.Format(r); got != "🇭🇰 Hong Kong SAR China HK¹⁺ AS13335 Hong Kong" {
+		t.Errorf("自定义模板输出错误: %q", got)
+	}
+
+	if _, err := NewTagFormatter(`{{.Loc`); err == nil {
+		t.Error("无效模板应返回错误")
+	}
+	if _, err := New(WithTagFormatter(nil)); err == nil {
+		t.Error("空模板应返回错误")
+	}
+
+	// 执行失败时回退到内置模板
+	bad, err := NewTagFormatter(`{{.NoSuchField}}`)
+	if err != nil {
+		t.Fatalf("解析模板失败: %v", err)
+	}
+	cli := &Client{tagFormatter: bad}
+	if got := cli.formatTag(r); got != "HK¹⁺" {
+		t.Errorf("回退标签错误: %q", got)
+	}
+
+	if flagEmoji("x1") != "" || countryName("ZZZ") != "ZZZ" || asnString(0) != "" {
+		t.Error("辅助函数处理无效输入错误")
+	}
+}
@@ -88,9 +88,9 @@ func cfCommonHeaders() map[string]string {
 	}
 }
 
-// shuffle 随机打乱字符串切片
-func shuffle(in []string) []string {
-	out := append([]string(nil), in...)
+// shuffle 随机打乱切片
+func shuffle[T any](in []T) []T {
+	out := append([]T(nil), in...)
 	rand.Shuffle(len(out), func(i, j int) {
 		out[i], out[j] = out[j], out[i]
 	})
@@ -0,0 +1,57 @@
+package ipinfo
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+// WARPMode Cloudflare WARP 出口类型
+type WARPMode string
+
+const (
+	WARPOff       WARPMode = ""           // 非 WARP 出口
+	WARPOn        WARPMode = "warp"       // 免费版 WARP
+	WARPPlus      WARPMode = "warp+"      // WARP+
+	WARPZeroTrust WARPMode = "zero_trust" // Zero Trust 网关
+)
+
+// warpEgressTable 内置 WARP 出口网段
+var warpEgressTable = sync.OnceValue(func() *PrefixTable[struct{}] {
+	t := &PrefixTable[struct{}]{}
+	p, err := ParseCDNRanges("warp", strings.NewReader(data.WARPEgressRanges()))
+	if err != nil {
+		return t
+	}
+	for _, prefix := range p.Prefixes {
+		t.Insert(prefix, struct{}{})
+	}
+	return t
+})
+
+// IsWARPEgress 判断 IP 是否位于 Cloudflare WARP 出口网段
+func IsWARPEgress(addr netip.Addr) bool {
+	_, _, ok := warpEgressTable().Lookup(addr)
+	return ok
+}
+
+// ClassifyWARP 根据 trace 的 warp=/gateway= 字段判断 WARP 类型;
+// trace 无相关字段时, 出口 IP 位于 WARP 出口网段则视为 WARPOn
+func ClassifyWARP(info *IPData, trace CFTrace) WARPMode {
+	switch {
+	case trace.Gateway == "on":
+		return WARPZeroTrust
+	case trace.WARP == "plus":
+		return WARPPlus
+	case trace.WARP == "on":
+		return WARPOn
+	case trace.WARP == "off":
+		return WARPOff
+	}
+	if info != nil {
+		return info.WARP
+	}
+	return WARPOff
+}
@@ -0,0 +1,74 @@
+package ipinfo
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sinspired/checkip/internal/data"
+)
+
+func TestIsWARPEgress(t *testing.T) {
+	tests := map[string]bool{
+		"104.28.163.56":  true,
+		"2a09:bac1::1":   true,
+		"104.16.132.229": false,
+		"8.8.8.8":        false,
+	}
+	for ip, want := range tests {
+		if got := IsWARPEgress(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("IsWARPEgress(%s) = %v, 期望 %v", ip, got, want)
+		}
+	}
+}
+
+func TestClassifyWARP(t *testing.T) {
+	egress := &IPData{WARP: WARPOn}
+	tests := []struct {
+		info  *IPData
+		trace CFTrace
+		want  WARPMode
+	}{
+		{nil, CFTrace{WARP: "on", Gateway: "on"}, WARPZeroTrust},
+		{nil, CFTrace{WARP: "plus", Gateway: "off"}, WARPPlus},
+		{nil, CFTrace{WARP: "on"}, WARPOn},
+		{egress, CFTrace{WARP: "off"}, WARPOff},
+		{egress, CFTrace{}, WARPOn}, // trace 获取失败时依据出口网段
+		{&IPData{}, CFTrace{}, WARPOff},
+	}
+	for i, tt := range tests {
+		if got := ClassifyWARP(tt.info, tt.trace); got != tt.want {
+			t.Errorf("用例 %d: ClassifyWARP = %q, 期望 %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCDNWARP(t *testing.T) {
+	db, err := data.OpenMaxMindDB("")
+	if err != nil {
+		t.Fatalf("打开 MaxMind 数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	cli, err := New(WithDBReader(db))
+	if err != nil {
+		t.Fatalf("初始化客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	info := &IPData{IPv4: "104.28.163.56"}
+	if !cli.CheckCDN(info) || info.CDN != CDNCloudflare || info.WARP != WARPOn {
+		t.Errorf("WARP 出口识别错误: %+v", info)
+	}
+
+	info = &IPData{IPv4: "104.16.132.229"}
+	if !cli.CheckCDN(info) || info.WARP != WARPOff {
+		t.Errorf("普通 Cloudflare 网段不应标记为 WARP: %+v", info)
+	}
+
+	// 传入 cfLoc 时不请求 trace, 仅依据出口网段
+	info = &IPData{IPv4: "104.28.163.56", CountryCode: "US"}
+	cli.CheckCDN(info)
+	if p := cli.GetCfProxyInfo(info, "US", "104.28.163.56"); p.warp != WARPOn {
+		t.Errorf("WARP 代理信息错误: %+v", p)
+	}
+}
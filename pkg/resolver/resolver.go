@@ -2,10 +2,13 @@
 package resolver
 
 import (
+	"context"
+	"log/slog"
 	"net"
 
 	"github.com/oschwald/maxminddb-golang/v2"
 	"github.com/sinspired/checkip/internal/resolver"
+	"github.com/sinspired/checkip/pkg/ipinfo"
 )
 
 // Resolver 提供IP检查功能
@@ -13,11 +16,45 @@ type Resolver struct {
 	resolver *resolver.Resolver
 }
 
-// NewResolver 创建一个新的解析器实例
+// NewResolver 创建一个新的解析器实例, 初始化失败时记录错误并返回 nil
+//
+// Deprecated: 使用 NewResolverWithOptions, 可设置内部的 ipinfo 客户端并返回初始化错误
 func NewResolver(cfCdnRanges map[string][]*net.IPNet, geoDB *maxminddb.Reader) *Resolver {
-	return &Resolver{
-		resolver: resolver.NewResolver(cfCdnRanges, geoDB),
+	r, err := NewResolverWithOptions(cfCdnRanges, geoDB)
+	if err != nil {
+		slog.Error("创建解析器失败", "error", err)
+		return nil
 	}
+	return r
+}
+
+// NewResolverWithOptions 创建一个新的解析器实例, opts 用于设置内部的 ipinfo 客户端; 初始化失败时返回错误
+func NewResolverWithOptions(cfCdnRanges map[string][]*net.IPNet, geoDB *maxminddb.Reader, opts ...ipinfo.Option) (*Resolver, error) {
+	r, err := resolver.NewResolver(cfCdnRanges, geoDB, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{resolver: r}, nil
+}
+
+// Reload 重新加载 CDN 网段文件
+func (c *Resolver) Reload() error {
+	return c.resolver.Reload()
+}
+
+// ReloadDB 重新加载可热替换的 MaxMind 数据库
+func (c *Resolver) ReloadDB() error {
+	return c.resolver.ReloadDB()
+}
+
+// Meta 返回已加载数据库的元数据及 Cloudflare 网段信息
+func (c *Resolver) Meta() *resolver.MetaResult {
+	return c.resolver.Meta()
+}
+
+// Close 释放解析器资源
+func (c *Resolver) Close() error {
+	return c.resolver.Close()
 }
 
 // Resolve 检查指定IP的信息
@@ -25,12 +62,27 @@ func (c *Resolver) Resolve(ip string) (*resolver.ResolveResult, error) {
 	return c.resolver.Resolve(ip)
 }
 
+// ResolveContext 检查指定IP的信息, 仅查询本地 CDN 网段及数据库
+func (c *Resolver) ResolveContext(ctx context.Context, ip string) (*resolver.ResolveResult, error) {
+	return c.resolver.ResolveContext(ctx, ip)
+}
+
 // GetCurrentIPInfo 获取当前IP的完整信息
 func (c *Resolver) GetCurrentIPInfo() (*resolver.ResolveResult, error) {
 	return c.resolver.GetCurrentIPInfo()
 }
 
+// GetCurrentIPInfoContext 获取当前IP的完整信息, ctx 取消时中止进行中的请求
+func (c *Resolver) GetCurrentIPInfoContext(ctx context.Context) (*resolver.ResolveResult, error) {
+	return c.resolver.GetCurrentIPInfoContext(ctx)
+}
+
 // GetCurrentIP 获取当前IP地址
 func (c *Resolver) GetCurrentIP() (string, error) {
 	return c.resolver.GetCurrentIP()
 }
+
+// GetCurrentIPContext 获取当前IP地址, ctx 取消时中止进行中的请求
+func (c *Resolver) GetCurrentIPContext(ctx context.Context) (string, error) {
+	return c.resolver.GetCurrentIPContext(ctx)
+}